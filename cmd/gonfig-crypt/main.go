@@ -0,0 +1,224 @@
+// Command gonfig-crypt generates keys, and encrypts, decrypts and rotates configuration values of the crypt package.
+//
+// The keys are read from the keyring file given by -keyring, otherwise from the GONFIG_KEYRING
+// or GONFIG_KEYRING_FILE environment variables.
+//
+//	gonfig-crypt keygen [-age]                    print a new AES key, or a new age identity
+//	gonfig-crypt encrypt [-type str] [value]      encrypt a value, read from stdin if omitted
+//	gonfig-crypt decrypt [value]                  decrypt a value, read from stdin if omitted
+//	gonfig-crypt rotate [-w] file...              re-encrypt every value of the files with the first key
+//
+// To rotate keys, add the new key as the first line of the keyring, run rotate, then remove the old key.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/soyacen/gonfig/crypt"
+)
+
+var Version = "v0.0.8"
+
+// encryptedValue matches the encrypted values in configuration files
+var encryptedValue = regexp.MustCompile(`ENC\[[A-Z0-9_]+(,[a-z]+:[A-Za-z0-9+/=]*)*\]`)
+
+func main() {
+	if len(os.Args) == 2 && os.Args[1] == "--version" {
+		fmt.Fprintf(os.Stdout, "%v %v\n", filepath.Base(os.Args[0]), Version)
+		return
+	}
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "keygen":
+		err = keygen(args)
+	case "encrypt":
+		err = encrypt(args)
+	case "decrypt":
+		err = decrypt(args)
+	case "rotate":
+		err = rotate(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", filepath.Base(os.Args[0]), err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %v keygen|encrypt|decrypt|rotate [flags] [args]\n", filepath.Base(os.Args[0]))
+	os.Exit(2)
+}
+
+// keyring reads the keyring of the -keyring flag, or of the environment
+func keyring(path string) (*crypt.Keyring, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if path != "" {
+		return crypt.FileKeyProvider(path).Keyring(ctx)
+	}
+	return crypt.DefaultKeyProvider().Keyring(ctx)
+}
+
+// input returns the single argument, or stdin without its trailing newline
+func input(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", errors.New("expected a single value")
+	}
+}
+
+func keygen(args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	ageKey := flags.Bool("age", false, "generate an age identity instead of an AES key")
+	_ = flags.Parse(args)
+	if *ageKey {
+		identity, err := age.GenerateX25519Identity()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# recipient: %s\n%s\n", identity.Recipient(), identity)
+		return nil
+	}
+	key, err := crypt.GenerateKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
+
+func encrypt(args []string) error {
+	flags := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keyringPath := flags.String("keyring", "", "path of the keyring file")
+	typ := flags.String("type", crypt.TypeString, "type of the value: str, int, float or bool")
+	_ = flags.Parse(args)
+	plaintext, err := input(flags.Args())
+	if err != nil {
+		return err
+	}
+	keys, err := keyring(*keyringPath)
+	if err != nil {
+		return err
+	}
+	value, err := keys.Encrypt(plaintext, *typ)
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func decrypt(args []string) error {
+	flags := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyringPath := flags.String("keyring", "", "path of the keyring file")
+	_ = flags.Parse(args)
+	value, err := input(flags.Args())
+	if err != nil {
+		return err
+	}
+	keys, err := keyring(*keyringPath)
+	if err != nil {
+		return err
+	}
+	plaintext, _, err := keys.Decrypt(value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(plaintext)
+	return nil
+}
+
+func rotate(args []string) error {
+	flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+	keyringPath := flags.String("keyring", "", "path of the keyring file")
+	write := flags.Bool("w", false, "write the result to the files instead of stdout")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		return errors.New("rotate: no files")
+	}
+	keys, err := keyring(*keyringPath)
+	if err != nil {
+		return err
+	}
+	for _, filename := range flags.Args() {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		rotated, n, err := rotateValues(keys, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if !*write {
+			_, err := os.Stdout.Write(rotated)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.WriteFile(filename, rotated, info.Mode().Perm()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: rotated %d values\n", filename, n)
+	}
+	return nil
+}
+
+// rotateValues re-encrypts every encrypted value of data, keeping the rest of data unchanged
+func rotateValues(keys *crypt.Keyring, data []byte) ([]byte, int, error) {
+	var rotated bytes.Buffer
+	var errs []error
+	last, n := 0, 0
+	for _, loc := range encryptedValue.FindAllIndex(data, -1) {
+		rotated.Write(data[last:loc[0]])
+		last = loc[1]
+		value := string(data[loc[0]:loc[1]])
+		line := 1 + bytes.Count(data[:loc[0]], []byte("\n"))
+		plaintext, typ, err := keys.Decrypt(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		encrypted, err := keys.Encrypt(plaintext, typ)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		rotated.WriteString(encrypted)
+		n++
+	}
+	rotated.Write(data[last:])
+	if err := errors.Join(errs...); err != nil {
+		return nil, 0, err
+	}
+	return rotated.Bytes(), n, nil
+}
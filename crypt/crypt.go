@@ -0,0 +1,354 @@
+// Package crypt encrypts and decrypts configuration values, so that configuration files with secrets can be committed.
+//
+// Encrypted values are strings in a SOPS-like format, e.g.
+//
+//	password: ENC[AES256_GCM,data:Tr7o...,iv:1tg...,tag:Fk8...,type:str]
+//	token: ENC[AGE,data:YWdl...,type:str]
+//
+// The type records the kind of the plaintext ("str", "int", "float" or "bool"), so that a decrypted value
+// has the same kind as before encryption. Use a Decrypter with resource.WithTransformers or resource.Transform
+// to decrypt the values of a resource, and the gonfig-crypt command to encrypt and rotate values.
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ resource.Transformer = (*Decrypter)(nil)
+
+const (
+	// AES256GCM is the cipher of values encrypted with an AES-256 key
+	AES256GCM = "AES256_GCM"
+	// Age is the cipher of values encrypted for age recipients
+	Age = "AGE"
+)
+
+// Types of plaintext values
+const (
+	TypeString = "str"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+)
+
+// ErrNoKey is returned when no key of the keyring decrypts a value
+var ErrNoKey = errors.New("crypt: no key decrypts the value")
+
+// IsEncrypted reports whether a string is an encrypted value
+//
+// Args:
+//
+//	value (string): String to check
+//
+// Returns:
+//
+//	bool: Whether the string has the format of an encrypted value
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, "ENC[") && strings.HasSuffix(value, "]")
+}
+
+// Encrypt encrypts a plaintext value with the first AES key of the keyring,
+// or for every age recipient if the keyring has no AES key
+//
+// Args:
+//
+//	plaintext (string): Value to encrypt, e.g. "8080" for an int
+//	typ (string): Type of the value, TypeString if empty
+//
+// Returns:
+//
+//	string: Encrypted value
+//	error: Error if the type is invalid or the keyring has no key encrypting values
+func (k *Keyring) Encrypt(plaintext string, typ string) (string, error) {
+	if typ == "" {
+		typ = TypeString
+	}
+	if _, err := typedValue(plaintext, typ); err != nil {
+		return "", err
+	}
+	switch {
+	case len(k.AESKeys) > 0:
+		block, err := aes.NewCipher(k.AESKeys[0])
+		if err != nil {
+			return "", err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return "", err
+		}
+		iv := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(iv); err != nil {
+			return "", err
+		}
+		sealed := gcm.Seal(nil, iv, []byte(plaintext), additionalData(typ))
+		data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+		return fmt.Sprintf("ENC[%s,data:%s,iv:%s,tag:%s,type:%s]", AES256GCM,
+			base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(iv),
+			base64.StdEncoding.EncodeToString(tag), typ), nil
+	case len(k.Recipients) > 0:
+		var buf bytes.Buffer
+		w, err := age.Encrypt(&buf, k.Recipients...)
+		if err != nil {
+			return "", fmt.Errorf("crypt: %w", err)
+		}
+		if _, err := io.WriteString(w, plaintext); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("ENC[%s,data:%s,type:%s]", Age, base64.StdEncoding.EncodeToString(buf.Bytes()), typ), nil
+	default:
+		return "", errors.New("crypt: the keyring has no AES key or age recipient to encrypt with")
+	}
+}
+
+// Decrypt decrypts an encrypted value.
+// Errors never contain the plaintext.
+//
+// Args:
+//
+//	value (string): Encrypted value
+//
+// Returns:
+//
+//	string: Plaintext value
+//	string: Type of the value
+//	error: Error if the value is malformed or no key decrypts it
+func (k *Keyring) Decrypt(value string) (string, string, error) {
+	if !IsEncrypted(value) {
+		return "", "", errors.New("crypt: not an encrypted value")
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "ENC["), "]"), ",")
+	fields := make(map[string][]byte, len(parts))
+	typ := TypeString
+	for _, part := range parts[1:] {
+		name, field, ok := strings.Cut(part, ":")
+		if !ok {
+			return "", "", errors.New("crypt: malformed encrypted value")
+		}
+		if name == "type" {
+			typ = field
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return "", "", fmt.Errorf("crypt: malformed %s of encrypted value", name)
+		}
+		fields[name] = data
+	}
+
+	switch parts[0] {
+	case AES256GCM:
+		for _, key := range k.AESKeys {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return "", "", err
+			}
+			gcm, err := cipher.NewGCM(block)
+			if err != nil {
+				return "", "", err
+			}
+			if len(fields["iv"]) != gcm.NonceSize() || len(fields["tag"]) != gcm.Overhead() {
+				return "", "", errors.New("crypt: malformed encrypted value")
+			}
+			plaintext, err := gcm.Open(nil, fields["iv"], append(fields["data"], fields["tag"]...), additionalData(typ))
+			if err == nil {
+				return string(plaintext), typ, nil
+			}
+		}
+		return "", "", ErrNoKey
+	case Age:
+		if len(k.Identities) == 0 {
+			return "", "", ErrNoKey
+		}
+		r, err := age.Decrypt(bytes.NewReader(fields["data"]), k.Identities...)
+		if err != nil {
+			var noMatch *age.NoIdentityMatchError
+			if errors.As(err, &noMatch) {
+				return "", "", ErrNoKey
+			}
+			return "", "", errors.New("crypt: malformed age value")
+		}
+		plaintext, err := io.ReadAll(r)
+		if err != nil {
+			return "", "", errors.New("crypt: malformed age value")
+		}
+		return string(plaintext), typ, nil
+	default:
+		return "", "", fmt.Errorf("crypt: unsupported cipher %q", parts[0])
+	}
+}
+
+// additionalData authenticates the type of a value together with its data
+func additionalData(typ string) []byte {
+	return []byte("type:" + typ)
+}
+
+// typedValue converts a plaintext to a value of its type, errors never contain the plaintext
+func typedValue(plaintext string, typ string) (*structpb.Value, error) {
+	switch typ {
+	case TypeString:
+		return structpb.NewStringValue(plaintext), nil
+	case TypeInt:
+		n, err := strconv.ParseInt(plaintext, 10, 64)
+		if err != nil {
+			return nil, errors.New("crypt: value is not an int")
+		}
+		// Integers beyond the precision of a double are kept as strings, as protojson does for int64
+		if n > 1<<53 || n < -(1<<53) {
+			return structpb.NewStringValue(plaintext), nil
+		}
+		return structpb.NewNumberValue(float64(n)), nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(plaintext, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, errors.New("crypt: value is not a float")
+		}
+		return structpb.NewNumberValue(f), nil
+	case TypeBool:
+		b, err := strconv.ParseBool(plaintext)
+		if err != nil {
+			return nil, errors.New("crypt: value is not a bool")
+		}
+		return structpb.NewBoolValue(b), nil
+	default:
+		return nil, fmt.Errorf("crypt: unsupported type %q", typ)
+	}
+}
+
+// Decrypter decrypts the encrypted string values of configuration data.
+// It implements resource.Transformer:
+//
+//	rsc, err := file.New("config.yaml", resource.WithTransformers(crypt.NewDecrypter(crypt.DefaultKeyProvider())))
+type Decrypter struct {
+	// provider provides the keys
+	provider KeyProvider
+}
+
+// NewDecrypter creates a Decrypter
+//
+// Args:
+//
+//	provider (KeyProvider): Provider of the keys, e.g. DefaultKeyProvider()
+//
+// Returns:
+//
+//	*Decrypter: New decrypter
+func NewDecrypter(provider KeyProvider) *Decrypter {
+	return &Decrypter{provider: provider}
+}
+
+// Transform returns a copy of the configuration data with every encrypted value decrypted.
+// The keys are only requested if the data contains encrypted values.
+// Errors name the field of the value and never contain the plaintext.
+// Decrypted values are marked with format.MarkSensitive in the sets attached to the context, so that the callers
+// can redact errors converting them into messages, e.g. an invalid enum value, see format.Sensitive.
+//
+// Args:
+//
+//	ctx (context.Context): Context for cancellation and timeouts
+//	value (*structpb.Struct): Configuration data
+//
+// Returns:
+//
+//	*structpb.Struct: Decrypted configuration data
+//	error: Error if the keys cannot be read or a value cannot be decrypted
+func (d *Decrypter) Transform(ctx context.Context, value *structpb.Struct) (*structpb.Struct, error) {
+	if !containsEncrypted(structpb.NewStructValue(value)) {
+		return value, nil
+	}
+	keyring, err := d.provider.Keyring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := decryptValue(ctx, keyring, "", structpb.NewStructValue(value))
+	if err != nil {
+		return nil, err
+	}
+	return decrypted.GetStructValue(), nil
+}
+
+// containsEncrypted reports whether a value contains an encrypted string
+func containsEncrypted(value *structpb.Value) bool {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return IsEncrypted(kind.StringValue)
+	case *structpb.Value_StructValue:
+		for _, field := range kind.StructValue.GetFields() {
+			if containsEncrypted(field) {
+				return true
+			}
+		}
+	case *structpb.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			if containsEncrypted(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptValue returns a copy of a value with the encrypted strings decrypted, path names the value in errors
+func decryptValue(ctx context.Context, keyring *Keyring, path string, value *structpb.Value) (*structpb.Value, error) {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		if !IsEncrypted(kind.StringValue) {
+			return value, nil
+		}
+		plaintext, typ, err := keyring.Decrypt(kind.StringValue)
+		if err != nil {
+			return nil, fmt.Errorf("%w (field %s)", err, path)
+		}
+		decrypted, err := typedValue(plaintext, typ)
+		if err != nil {
+			return nil, fmt.Errorf("%w (field %s)", err, path)
+		}
+		// Record the replaced field, so that errors converting it into a message do not contain the plaintext
+		format.MarkSensitive(ctx, path, decrypted)
+		return decrypted, nil
+	case *structpb.Value_StructValue:
+		fields := make(map[string]*structpb.Value, len(kind.StructValue.GetFields()))
+		for key, field := range kind.StructValue.GetFields() {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			decrypted, err := decryptValue(ctx, keyring, fieldPath, field)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = decrypted
+		}
+		return structpb.NewStructValue(&structpb.Struct{Fields: fields}), nil
+	case *structpb.Value_ListValue:
+		values := make([]*structpb.Value, len(kind.ListValue.GetValues()))
+		for i, item := range kind.ListValue.GetValues() {
+			decrypted, err := decryptValue(ctx, keyring, fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = decrypted
+		}
+		return structpb.NewListValue(&structpb.ListValue{Values: values}), nil
+	default:
+		return value, nil
+	}
+}
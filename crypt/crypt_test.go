@@ -0,0 +1,248 @@
+package crypt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/soyacen/gonfig"
+	_ "github.com/soyacen/gonfig/format/yaml"
+	"github.com/soyacen/gonfig/resource"
+	"github.com/soyacen/gonfig/resource/file"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newKeyring creates a keyring with a new AES key
+func newKeyring(t *testing.T) *Keyring {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := ParseKeyring(strings.NewReader("# test key\n" + key + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+// TestKeyring_Encrypt tests encryption and decryption of values with AES and age keys.
+func TestKeyring_Encrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageKeyring, err := ParseKeyring(strings.NewReader(identity.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyrings := map[string]*Keyring{"AES": newKeyring(t), "Age": ageKeyring}
+	for name, keyring := range keyrings {
+		t.Run(name, func(t *testing.T) {
+			value, err := keyring.Encrypt("s3cret", TypeString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEncrypted(value) || strings.Contains(value, "s3cret") {
+				t.Fatalf("expected encrypted value; got %q", value)
+			}
+			plaintext, typ, err := keyring.Decrypt(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plaintext != "s3cret" || typ != TypeString {
+				t.Errorf("expected s3cret of type str; got %q of type %q", plaintext, typ)
+			}
+			if _, _, err := newKeyring(t).Decrypt(value); !errors.Is(err, ErrNoKey) {
+				t.Errorf("expected ErrNoKey for another keyring; got %v", err)
+			}
+		})
+	}
+}
+
+// TestKeyring_Rotation tests that values encrypted with an old key decrypt while it is kept in the keyring.
+func TestKeyring_Rotation(t *testing.T) {
+	old, current := newKeyring(t), newKeyring(t)
+	value, err := old.Encrypt("s3cret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := &Keyring{AESKeys: append(current.AESKeys, old.AESKeys...)}
+	if plaintext, _, err := rotated.Decrypt(value); err != nil || plaintext != "s3cret" {
+		t.Errorf("expected s3cret; got %q, %v", plaintext, err)
+	}
+}
+
+// TestDecrypter_Transform tests decryption of the values of configuration data.
+func TestDecrypter_Transform(t *testing.T) {
+	keyring := newKeyring(t)
+	encrypt := func(plaintext string, typ string) string {
+		value, err := keyring.Encrypt(plaintext, typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	value, err := structpb.NewStruct(map[string]any{
+		"host":     "localhost",
+		"password": encrypt("s3cret", TypeString),
+		"port":     encrypt("6379", TypeInt),
+		"tls":      encrypt("true", TypeBool),
+		"tokens":   []any{encrypt("a", ""), "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := NewDecrypter(keyring).Transform(context.Background(), value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"host":     "localhost",
+		"password": "s3cret",
+		"port":     float64(6379),
+		"tls":      true,
+		"tokens":   []any{"a", "b"},
+	}
+	if !reflect.DeepEqual(expected, decrypted.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, decrypted.AsMap())
+	}
+	if !IsEncrypted(value.GetFields()["password"].GetStringValue()) {
+		t.Errorf("expected the input to be unchanged")
+	}
+}
+
+// TestDecrypter_Transform_Invalid tests that errors name the field and never contain the plaintext.
+func TestDecrypter_Transform_Invalid(t *testing.T) {
+	keyring := newKeyring(t)
+	value, err := keyring.Encrypt("s3cret", TypeString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tampering with the type fails authentication instead of converting the plaintext
+	tampered := strings.Replace(value, "type:str", "type:int", 1)
+	tests := []struct {
+		name     string
+		value    string
+		provider KeyProvider
+	}{
+		{"Wrong Key", value, newKeyring(t)},
+		{"Tampered Type", tampered, keyring},
+		{"Malformed", "ENC[AES256_GCM,data:!!!]", keyring},
+		{"Missing Keys", value, KeyProviderFunc(func(context.Context) (*Keyring, error) {
+			return nil, errors.New("crypt: no keyring")
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := structpb.NewStruct(map[string]any{"redis": map[string]any{"password": tt.value}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = NewDecrypter(tt.provider).Transform(context.Background(), data)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("expected error without plaintext; got %v", err)
+			}
+			if tt.name != "Missing Keys" && !strings.Contains(err.Error(), "redis.password") {
+				t.Errorf("expected error naming the field; got %v", err)
+			}
+		})
+	}
+}
+
+// TestDecrypter_ConvertError tests that errors converting decrypted values into messages,
+// returned by Load or decoding watched values, do not contain the plaintext.
+func TestDecrypter_ConvertError(t *testing.T) {
+	keyring := newKeyring(t)
+	decrypter := NewDecrypter(keyring)
+	// write writes a configuration with an encrypted value that is not a valid enum value
+	write := func(filename string, javaPackage string) {
+		t.Helper()
+		value, err := keyring.Encrypt("hunter2-SECRET", TypeString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := "javaPackage: " + javaPackage + "\noptimizeFor: " + value + "\n"
+		if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// check checks that an error names the field and redacts the plaintext
+	check := func(err error) {
+		t.Helper()
+		var convertErr *gonfig.ConvertError
+		if !errors.As(err, &convertErr) || convertErr.Path != "optimizeFor" {
+			t.Fatalf("expected conversion error of field optimizeFor; got %v", err)
+		}
+		if strings.Contains(err.Error(), "hunter2-SECRET") || !strings.Contains(err.Error(), "[redacted]") {
+			t.Errorf("expected error without plaintext; got %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		new  func(filename string) (resource.Resource, error)
+	}{
+		{"WithTransformers", func(filename string) (resource.Resource, error) {
+			return file.New(filename, resource.WithTransformers(decrypter))
+		}},
+		{"Transform", func(filename string) (resource.Resource, error) {
+			rsc, err := file.New(filename)
+			if err != nil {
+				return nil, err
+			}
+			return resource.Wrap(rsc, resource.Transform(decrypter)), nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			filename := filepath.Join(t.TempDir(), "config.yaml")
+			write(filename, "a")
+			rsc, err := tt.new(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = gonfig.Load[*descriptorpb.FileOptions](ctx, rsc)
+			check(err)
+
+			c := make(chan *structpb.Struct, 16)
+			stop, err := rsc.Watch(ctx, func(value *structpb.Struct) { c <- value }, func(err error) { t.Logf("Error: %v", err) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stop(ctx)
+			write(filename, "b")
+			for notified := false; !notified; {
+				select {
+				case value := <-c:
+					notified = value.GetFields()["javaPackage"].GetStringValue() == "b"
+				case <-ctx.Done():
+					t.Fatal("expected notification; got timeout")
+				}
+			}
+			check(rsc.(resource.MessageResource).DecodeMessage(&descriptorpb.FileOptions{}))
+		})
+	}
+}
+
+// TestParseKeyring_Invalid tests that keyring errors never contain key material.
+func TestParseKeyring_Invalid(t *testing.T) {
+	_, err := ParseKeyring(strings.NewReader("# comment\nnot-a-key-material\n"))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if strings.Contains(err.Error(), "not-a-key-material") || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error naming the line only; got %v", err)
+	}
+}
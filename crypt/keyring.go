@@ -0,0 +1,196 @@
+package crypt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const (
+	// KeyringEnv is the environment variable holding the keys read by DefaultKeyProvider
+	KeyringEnv = "GONFIG_KEYRING"
+	// KeyringFileEnv is the environment variable holding the path of the keyring file read by DefaultKeyProvider
+	KeyringFileEnv = "GONFIG_KEYRING_FILE"
+)
+
+// Keyring holds the keys encrypting and decrypting values.
+//
+// A keyring file has one key per line, blank lines and lines starting with '#' are ignored:
+//   - a base64-encoded 32-byte AES-256 key, as generated by GenerateKey
+//   - an age X25519 identity, "AGE-SECRET-KEY-1...", decrypting values encrypted for its recipient
+//   - an age X25519 recipient, "age1...", only encrypting values
+//
+// Values are decrypted with every key of the keyring, so that old keys can be kept while values are rotated.
+// New values are encrypted with the first AES key, or for every age recipient if the keyring has no AES key.
+type Keyring struct {
+	// AESKeys are the AES-256 keys, the first one encrypts new values
+	AESKeys [][]byte
+	// Identities decrypt values encrypted with age
+	Identities []age.Identity
+	// Recipients encrypt new values with age if there is no AES key
+	Recipients []age.Recipient
+}
+
+// KeyProvider provides the keys decrypting values.
+// It is called on every load and change, so that the keys can be rotated without restarting.
+type KeyProvider interface {
+	// Keyring returns the current keys
+	//
+	// Args:
+	//   ctx (context.Context): Context for cancellation and timeouts
+	//
+	// Returns:
+	//   *Keyring: Current keys
+	//   error: Error if the keys cannot be read
+	Keyring(ctx context.Context) (*Keyring, error)
+}
+
+// KeyProviderFunc adapts a function to the KeyProvider interface, e.g. to read keys from a secret manager
+type KeyProviderFunc func(ctx context.Context) (*Keyring, error)
+
+// Keyring calls the function
+func (f KeyProviderFunc) Keyring(ctx context.Context) (*Keyring, error) {
+	return f(ctx)
+}
+
+// Keyring returns the keyring itself, so that a Keyring is a KeyProvider of fixed keys
+func (k *Keyring) Keyring(context.Context) (*Keyring, error) {
+	return k, nil
+}
+
+// FileKeyProvider reads the keys from a keyring file
+//
+// Args:
+//
+//	path (string): Path of the keyring file
+//
+// Returns:
+//
+//	KeyProvider: Provider reading the file on every call
+func FileKeyProvider(path string) KeyProvider {
+	return KeyProviderFunc(func(context.Context) (*Keyring, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("crypt: %w", err)
+		}
+		defer f.Close()
+		keyring, err := ParseKeyring(f)
+		if err != nil {
+			return nil, fmt.Errorf("crypt: %s: %w", path, err)
+		}
+		return keyring, nil
+	})
+}
+
+// EnvKeyProvider reads the keys from an environment variable in the keyring file format,
+// where keys may also be separated by commas
+//
+// Args:
+//
+//	name (string): Name of the environment variable
+//
+// Returns:
+//
+//	KeyProvider: Provider reading the variable on every call
+func EnvKeyProvider(name string) KeyProvider {
+	return KeyProviderFunc(func(context.Context) (*Keyring, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("crypt: environment variable %s is not set", name)
+		}
+		keyring, err := ParseKeyring(strings.NewReader(strings.ReplaceAll(value, ",", "\n")))
+		if err != nil {
+			return nil, fmt.Errorf("crypt: %s: %w", name, err)
+		}
+		return keyring, nil
+	})
+}
+
+// DefaultKeyProvider reads the keys from the GONFIG_KEYRING environment variable if set,
+// otherwise from the keyring file named by GONFIG_KEYRING_FILE.
+//
+// Returns:
+//
+//	KeyProvider: Provider of the keys configured by the environment
+func DefaultKeyProvider() KeyProvider {
+	return KeyProviderFunc(func(ctx context.Context) (*Keyring, error) {
+		if _, ok := os.LookupEnv(KeyringEnv); ok {
+			return EnvKeyProvider(KeyringEnv).Keyring(ctx)
+		}
+		if path, ok := os.LookupEnv(KeyringFileEnv); ok {
+			return FileKeyProvider(path).Keyring(ctx)
+		}
+		return nil, fmt.Errorf("crypt: neither %s nor %s is set", KeyringEnv, KeyringFileEnv)
+	})
+}
+
+// ParseKeyring parses keys in the keyring file format.
+// Errors refer to lines by number and never contain key material.
+//
+// Args:
+//
+//	r (io.Reader): Keyring data
+//
+// Returns:
+//
+//	*Keyring: Parsed keys
+//	error: Error if a line is not a valid key
+func ParseKeyring(r io.Reader) (*Keyring, error) {
+	keyring := &Keyring{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "AGE-SECRET-KEY-1"):
+			identity, err := age.ParseX25519Identity(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid age identity", n)
+			}
+			keyring.Identities = append(keyring.Identities, identity)
+			keyring.Recipients = append(keyring.Recipients, identity.Recipient())
+		case strings.HasPrefix(line, "age1"):
+			recipient, err := age.ParseX25519Recipient(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid age recipient", n)
+			}
+			keyring.Recipients = append(keyring.Recipients, recipient)
+		default:
+			key, err := base64.StdEncoding.DecodeString(line)
+			if err != nil || len(key) != 32 {
+				return nil, fmt.Errorf("line %d: invalid key, expected a base64-encoded 32-byte AES key or an age key", n)
+			}
+			keyring.AESKeys = append(keyring.AESKeys, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keyring.AESKeys) == 0 && len(keyring.Identities) == 0 && len(keyring.Recipients) == 0 {
+		return nil, errors.New("no keys")
+	}
+	return keyring, nil
+}
+
+// GenerateKey generates a random AES-256 key in the keyring file format
+//
+// Returns:
+//
+//	string: Base64-encoded key
+//	error: Error if the random source fails
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,69 @@
+package binpb
+
+import (
+	"fmt"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Binpb formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("binpb", Binpb{})
+}
+
+// Binpb implements the Formatter and MessageFormatter interfaces for the protobuf binary wire format.
+//
+// Data is decoded directly into the target message when loaded with gonfig.Load or gonfig.Watch.
+// The wire format does not name its message type, so Parse needs MessageName and the type must be
+// linked into the binary.
+type Binpb struct {
+	// MessageName is the full name of the message type used by Parse, e.g. "example.Config"
+	MessageName protoreflect.FullName
+}
+
+// Unmarshal decodes protobuf binary data into a message.
+//
+// Args:
+//
+//	data ([]byte): The protobuf binary data to be decoded
+//	message (proto.Message): Message the data is decoded into, it is reset first
+//
+// Returns:
+//
+//	error: An error if decoding fails
+func (Binpb) Unmarshal(data []byte, message proto.Message) error {
+	proto.Reset(message)
+	if err := proto.Unmarshal(data, message); err != nil {
+		return fmt.Errorf("binpb: %w", err)
+	}
+	return nil
+}
+
+// Parse converts protobuf binary data into a Protocol Buffer Struct object using the protojson mapping.
+//
+// Args:
+//
+//	data ([]byte): The protobuf binary data to be parsed
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if MessageName is not set or parsing fails
+func (b Binpb) Parse(data []byte) (*structpb.Struct, error) {
+	if b.MessageName == "" {
+		return nil, fmt.Errorf("binpb: message type is unknown, load with gonfig.Load or set MessageName")
+	}
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(b.MessageName)
+	if err != nil {
+		return nil, fmt.Errorf("binpb: %w", err)
+	}
+	message := messageType.New().Interface()
+	if err := b.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	return format.StructFromMessage(message)
+}
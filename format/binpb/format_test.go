@@ -0,0 +1,51 @@
+package binpb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyacen/gonfig/test"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestUnmarshal_Success tests successful decoding of protobuf binary data into a message.
+func TestUnmarshal_Success(t *testing.T) {
+	expected := &test.Config{Field1: "a", Field2: "b"}
+	data, err := proto.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &test.Config{Field1: "stale"}
+	if err := (Binpb{}).Unmarshal(data, config); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(config, expected) {
+		t.Errorf("expected %v; got %v", expected, config)
+	}
+}
+
+// TestParse_Success tests successful parsing of protobuf binary data with a known message type.
+func TestParse_Success(t *testing.T) {
+	data, err := proto.Marshal(&test.Config{Field1: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Binpb{MessageName: (&test.Config{}).ProtoReflect().Descriptor().FullName()}.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"field1": "a"}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+}
+
+// TestParse_InvalidBinpb tests error handling for invalid data and unknown message types.
+func TestParse_InvalidBinpb(t *testing.T) {
+	if _, err := (Binpb{}).Parse(nil); err == nil {
+		t.Error("expected error for unknown message type")
+	}
+	if _, err := (Binpb{MessageName: (&test.Config{}).ProtoReflect().Descriptor().FullName()}).Parse([]byte{0xff}); err == nil {
+		t.Error("expected error for invalid data")
+	}
+}
@@ -0,0 +1,340 @@
+package format
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Compression formats detected by WithDecompression
+const (
+	// Gzip is the extension of gzip-compressed data, e.g. "config.yaml.gz"
+	Gzip = "gz"
+	// Zstd is the extension of zstd-compressed data, e.g. "config.yaml.zst"
+	Zstd = "zst"
+)
+
+// MaxDecompressedSize is the maximum size in bytes of decompressed data, 64 MiB by default.
+// Reading more fails with ErrDecompressedTooLarge, so that a small compressed payload cannot expand without bound.
+// Set it before the sources are loaded.
+var MaxDecompressedSize int64 = 64 << 20
+
+// ErrDecompressedTooLarge is returned when decompressed data exceeds MaxDecompressedSize.
+var ErrDecompressedTooLarge = errors.New("decompressed data exceeds the maximum size")
+
+var (
+	// gzipMagic starts gzip-compressed data
+	gzipMagic = []byte{0x1f, 0x8b}
+	// zstdMagic starts zstd-compressed data
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ReaderFormatter is a Formatter parsing data from a stream, so that decompressed data is not buffered
+type ReaderFormatter interface {
+	Formatter
+	// ParseReader parses data read from r
+	//
+	// Args:
+	//   r (io.Reader): Raw configuration data
+	//
+	// Returns:
+	//   *structpb.Struct: Parsed configuration data
+	//   error: Error if reading or parsing fails
+	ParseReader(r io.Reader) (*structpb.Struct, error)
+}
+
+// SplitCompression splits the compression extension from a name, e.g. "config.yaml.gz" becomes "config.yaml" and "gz"
+//
+// Args:
+//
+//	name (string): Name of the source
+//
+// Returns:
+//
+//	string: Name without the compression extension
+//	string: Gzip, Zstd, or empty if the name has no compression extension
+func SplitCompression(name string) (string, string) {
+	switch ext := path.Ext(name); ext {
+	case "." + Gzip, "." + Zstd:
+		return name[:len(name)-len(ext)], ext[1:]
+	default:
+		return name, ""
+	}
+}
+
+// Compression detects the compression of data by its magic bytes
+//
+// Args:
+//
+//	data ([]byte): Raw data
+//
+// Returns:
+//
+//	string: Gzip, Zstd, or empty if the data is not compressed
+func Compression(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(data, zstdMagic):
+		return Zstd
+	default:
+		return ""
+	}
+}
+
+// Decompress returns a reader of the decompressed data, or of data itself if it is not compressed.
+// Reading more than MaxDecompressedSize bytes of decompressed data fails with ErrDecompressedTooLarge.
+//
+// Args:
+//
+//	data ([]byte): Raw data, compressed or not
+//
+// Returns:
+//
+//	io.ReadCloser: Reader of the decompressed data
+//	error: Error if the compressed data is malformed
+func Decompress(data []byte) (io.ReadCloser, error) {
+	switch Compression(data) {
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gz: %w", err)
+		}
+		return &limitedReader{ReadCloser: r, remaining: MaxDecompressedSize}, nil
+	case Zstd:
+		r, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("zst: %w", err)
+		}
+		return &limitedReader{ReadCloser: r.IOReadCloser(), remaining: MaxDecompressedSize}, nil
+	default:
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// limitedReader reads decompressed data up to a maximum size, failing with ErrDecompressedTooLarge beyond it
+type limitedReader struct {
+	io.ReadCloser
+	// remaining is the number of bytes that may still be read
+	remaining int64
+	// exceeded reports whether the data continued past the maximum size
+	exceeded bool
+}
+
+// Read reads at most the remaining bytes, and fails if the data continues past them
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		var probe [1]byte
+		n, err := r.ReadCloser.Read(probe[:])
+		if n > 0 {
+			r.exceeded = true
+			return 0, ErrDecompressedTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
+
+// EqualDecompressed reports whether two payloads have the same decompressed content.
+// Compressed payloads are compared as streams, so that recompressing unchanged content,
+// e.g. with a new gzip timestamp, is not a change.
+//
+// Args:
+//
+//	a ([]byte): Raw data, compressed or not
+//	b ([]byte): Raw data, compressed or not
+//
+// Returns:
+//
+//	bool: Whether the decompressed contents are equal, false if either is malformed
+func EqualDecompressed(a []byte, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	if Compression(a) == "" && Compression(b) == "" {
+		return false
+	}
+	ra, err := Decompress(a)
+	if err != nil {
+		return false
+	}
+	defer ra.Close()
+	rb, err := Decompress(b)
+	if err != nil {
+		return false
+	}
+	defer rb.Close()
+	chunkA, chunkB := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		na, errA := io.ReadFull(ra, chunkA)
+		nb, errB := io.ReadFull(rb, chunkB)
+		if !bytes.Equal(chunkA[:na], chunkB[:nb]) {
+			return false
+		}
+		endA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		endB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		switch {
+		case endA && endB:
+			return true
+		case endA != endB:
+			return false
+		case errA != nil || errB != nil:
+			return false
+		}
+	}
+}
+
+// WithDecompression decompresses gzip or zstd data, detected by its magic bytes, before it is parsed.
+// Uncompressed data is parsed as is. Decompressed data is streamed to formatters implementing ReaderFormatter,
+// and limited to MaxDecompressedSize bytes.
+// It is applied by the built-in resources, so that "config.yaml.gz" or compressed Consul values are decoded transparently.
+//
+// Args:
+//
+//	compression (string): Gzip or Zstd to reject data that is not compressed, e.g. for a name ending with ".gz";
+//	empty to detect the compression
+//
+// Returns:
+//
+//	Option: Option for Register
+func WithDecompression(compression string) Option {
+	return func(formatter Formatter) (Formatter, error) {
+		return newDecompressFormatter(formatter, compression), nil
+	}
+}
+
+// newDecompressFormatter creates a decompressFormatter, keeping the Unmarshal method of a MessageFormatter
+func newDecompressFormatter(formatter Formatter, compression string) Formatter {
+	f := &decompressFormatter{formatter: formatter, compression: compression}
+	if _, ok := formatter.(MessageFormatter); ok {
+		return &decompressMessageFormatter{decompressFormatter: f}
+	}
+	return f
+}
+
+// decompressFormatter decompresses data before parsing it with a formatter
+type decompressFormatter struct {
+	formatter Formatter
+	// compression is the expected compression, empty if it is detected
+	compression string
+}
+
+// Parse decompresses data and parses the result with the formatter
+func (f *decompressFormatter) Parse(data []byte) (*structpb.Struct, error) {
+	if err := f.check(data); err != nil {
+		return nil, err
+	}
+	if Compression(data) == "" {
+		return f.formatter.Parse(data)
+	}
+	r, err := Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if readerFormatter, ok := f.formatter.(ReaderFormatter); ok {
+		value, err := readerFormatter.ParseReader(r)
+		// Formatters may not wrap read errors
+		if limited, ok := r.(*limitedReader); ok && limited.exceeded {
+			return nil, fmt.Errorf("%s: %w", Compression(data), ErrDecompressedTooLarge)
+		}
+		return value, err
+	}
+	decompressed, err := f.readAll(r, data)
+	if err != nil {
+		return nil, err
+	}
+	return f.formatter.Parse(decompressed)
+}
+
+// WithSource binds the formatter to a source
+func (f *decompressFormatter) WithSource(name string) Formatter {
+	formatter := f.formatter
+	if sourceFormatter, ok := formatter.(SourceFormatter); ok {
+		formatter = sourceFormatter.WithSource(name)
+	}
+	return newDecompressFormatter(formatter, f.compression)
+}
+
+// Dependencies returns the dependencies of the formatter if it is a DependentFormatter
+func (f *decompressFormatter) Dependencies() []string {
+	if dependentFormatter, ok := f.formatter.(DependentFormatter); ok {
+		return dependentFormatter.Dependencies()
+	}
+	return nil
+}
+
+// Locate decompresses data and locates the field with the formatter if it is a Locator
+func (f *decompressFormatter) Locate(data []byte, path string) (Position, bool) {
+	locator, ok := f.formatter.(Locator)
+	if !ok || f.check(data) != nil {
+		return Position{}, false
+	}
+	if Compression(data) == "" {
+		return locator.Locate(data, path)
+	}
+	r, err := Decompress(data)
+	if err != nil {
+		return Position{}, false
+	}
+	defer r.Close()
+	decompressed, err := f.readAll(r, data)
+	if err != nil {
+		return Position{}, false
+	}
+	return locator.Locate(decompressed, path)
+}
+
+// check rejects data that is not compressed with the expected compression
+func (f *decompressFormatter) check(data []byte) error {
+	if f.compression != "" && Compression(data) != f.compression {
+		return fmt.Errorf("%s: data is not %s-compressed", f.compression, f.compression)
+	}
+	return nil
+}
+
+// readAll reads the decompressed data, prefixing errors with the compression
+func (f *decompressFormatter) readAll(r io.Reader, data []byte) ([]byte, error) {
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", Compression(data), err)
+	}
+	return decompressed, nil
+}
+
+// decompressMessageFormatter is a decompressFormatter keeping the Unmarshal method of a MessageFormatter
+type decompressMessageFormatter struct {
+	*decompressFormatter
+}
+
+// Unmarshal decompresses data and decodes the result into a message with the formatter
+func (f *decompressMessageFormatter) Unmarshal(data []byte, message proto.Message) error {
+	if err := f.check(data); err != nil {
+		return err
+	}
+	if Compression(data) == "" {
+		return f.formatter.(MessageFormatter).Unmarshal(data, message)
+	}
+	r, err := Decompress(data)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	decompressed, err := f.readAll(r, data)
+	if err != nil {
+		return err
+	}
+	return f.formatter.(MessageFormatter).Unmarshal(decompressed, message)
+}
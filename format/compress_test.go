@@ -0,0 +1,155 @@
+package format_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/json"
+	"github.com/soyacen/gonfig/format/yaml"
+)
+
+// gzipData compresses data with gzip, recording a modification time in the header
+func gzipData(t *testing.T, data string, modTime time.Time) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.ModTime = modTime
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// zstdData compresses data with zstd
+func zstdData(t *testing.T, data string) []byte {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll([]byte(data), nil)
+}
+
+// TestWithDecompression tests parsing of compressed and uncompressed data.
+func TestWithDecompression(t *testing.T) {
+	content := "routes:\n  - path: /api\n    upstream: api:8080\n"
+	expected := map[string]any{"routes": []any{map[string]any{"path": "/api", "upstream": "api:8080"}}}
+	tests := []struct {
+		name        string
+		compression string
+		data        []byte
+		wantErr     bool
+	}{
+		{"Gzip", "", gzipData(t, content, time.Time{}), false},
+		{"Zstd", "", zstdData(t, content), false},
+		{"Uncompressed", "", []byte(content), false},
+		{"Gzip Extension", format.Gzip, gzipData(t, content, time.Time{}), false},
+		{"Not Compressed", format.Zstd, []byte(content), true},
+		{"Truncated", "", gzipData(t, content, time.Time{})[:20], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := apply(t, yaml.Yaml{}, format.WithDecompression(tt.compression)).Parse(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v; got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(expected, value.AsMap()) {
+				t.Errorf("expected %v; got %v", expected, value.AsMap())
+			}
+		})
+	}
+
+	// Formatters without ParseReader get the decompressed data
+	value, err := apply(t, json.Json{}, format.WithDecompression("")).Parse(zstdData(t, `{"key": "value"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["key"].GetStringValue(); got != "value" {
+		t.Errorf("expected 'value'; got %q", got)
+	}
+}
+
+// TestWithDecompression_MaxSize tests that decompressed data larger than the maximum size is rejected.
+func TestWithDecompression_MaxSize(t *testing.T) {
+	defer func(size int64) { format.MaxDecompressedSize = size }(format.MaxDecompressedSize)
+	format.MaxDecompressedSize = 1024
+	content := `{"key": "` + strings.Repeat("a", 1024) + `"}`
+	tests := []struct {
+		name      string
+		formatter format.Formatter
+		data      []byte
+	}{
+		{"Gzip", json.Json{}, gzipData(t, content, time.Time{})},
+		{"Zstd", json.Json{}, zstdData(t, content)},
+		{"Reader", yaml.Yaml{}, gzipData(t, content, time.Time{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := apply(t, tt.formatter, format.WithDecompression("")).Parse(tt.data)
+			if !errors.Is(err, format.ErrDecompressedTooLarge) {
+				t.Errorf("expected ErrDecompressedTooLarge; got %v", err)
+			}
+		})
+	}
+
+	// Data of exactly the maximum size is accepted
+	content = `{"key": "` + strings.Repeat("a", 1024-len(`{"key": ""}`)) + `"}`
+	if _, err := apply(t, json.Json{}, format.WithDecompression("")).Parse(gzipData(t, content, time.Time{})); err != nil {
+		t.Errorf("expected no error; got %v", err)
+	}
+}
+
+// TestEqualDecompressed tests comparison of payloads by their decompressed content.
+func TestEqualDecompressed(t *testing.T) {
+	a := gzipData(t, "key: value\n", time.Unix(1, 0))
+	b := gzipData(t, "key: value\n", time.Unix(2, 0))
+	if bytes.Equal(a, b) {
+		t.Fatal("expected the gzip headers to differ")
+	}
+	tests := []struct {
+		name     string
+		a, b     []byte
+		expected bool
+	}{
+		{"Recompressed", a, b, true},
+		{"Different Compression", a, zstdData(t, "key: value\n"), true},
+		{"Compressed And Plain", a, []byte("key: value\n"), true},
+		{"Changed", a, gzipData(t, "key: other\n", time.Unix(1, 0)), false},
+		{"Prefix", a, gzipData(t, "key: value\nmore: data\n", time.Unix(1, 0)), false},
+		{"Plain", []byte("a"), []byte("b"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format.EqualDecompressed(tt.a, tt.b); got != tt.expected {
+				t.Errorf("expected %v; got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSplitCompression tests splitting of compression extensions from names.
+func TestSplitCompression(t *testing.T) {
+	tests := []struct {
+		name, base, compression string
+	}{
+		{"config.yaml.gz", "config.yaml", format.Gzip},
+		{"routes/config.json.zst", "routes/config.json", format.Zstd},
+		{"config.yaml", "config.yaml", ""},
+		{"config", "config", ""},
+	}
+	for _, tt := range tests {
+		base, compression := format.SplitCompression(tt.name)
+		if base != tt.base || compression != tt.compression {
+			t.Errorf("SplitCompression(%q) = %q, %q; want %q, %q", tt.name, base, compression, tt.base, tt.compression)
+		}
+	}
+}
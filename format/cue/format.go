@@ -0,0 +1,116 @@
+// Package cue provides a formatter evaluating CUE configuration files
+package cue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ format.SourceFormatter = Cue{}
+
+// DefaultDefinition is the definition of a schema the configuration is unified with
+// when Cue.Definition is empty. Schemas generated by Schema declare it.
+const DefaultDefinition = "#Config"
+
+// init registers the Cue formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("cue", Cue{})
+}
+
+// Cue implements the Formatter interface for CUE format.
+// The file is evaluated with its constraints and defaults, and the resulting value must be concrete.
+// Errors name the source the formatter is bound to by WithSource, which the resources do automatically.
+//
+// To check files at load time, re-register the formatter with a schema, for example one generated
+// from the proto config message:
+//
+//	format.RegisterFormatter("cue", cue.Cue{Schema: cue.Schema((&configs.Config{}).ProtoReflect().Descriptor())})
+type Cue struct {
+	// Schema is optional CUE source whose Definition is unified with the configuration
+	Schema string
+	// Definition is the path of the definition in Schema, DefaultDefinition if empty
+	Definition string
+
+	// filename is the name of the source, set by WithSource
+	filename string
+}
+
+// WithSource returns a formatter naming the source in the positions of errors.
+//
+// Args:
+//
+//	name (string): Name of the CUE source, e.g. the path of the file
+//
+// Returns:
+//
+//	format.Formatter: Formatter bound to the source
+func (c Cue) WithSource(name string) format.Formatter {
+	c.filename = name
+	return c
+}
+
+// Parse evaluates CUE-formatted byte data and exports it into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The CUE-formatted byte slice to be evaluated, it must evaluate to a concrete struct
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the evaluated data
+//	error: An error with positions if evaluation or validation fails
+func (c Cue) Parse(data []byte) (*structpb.Struct, error) {
+	ctx := cuecontext.New()
+	filename := c.filename
+	if filename == "" {
+		filename = "config.cue"
+	}
+	value := ctx.CompileBytes(data, cue.Filename(filename))
+	if err := value.Err(); err != nil {
+		return nil, wrapError(err)
+	}
+
+	if c.Schema != "" {
+		schema := ctx.CompileString(c.Schema, cue.Filename("schema.cue"))
+		if err := schema.Err(); err != nil {
+			return nil, wrapError(err)
+		}
+		definition := c.Definition
+		if definition == "" {
+			definition = DefaultDefinition
+		}
+		def := schema.LookupPath(cue.ParsePath(definition))
+		if !def.Exists() {
+			return nil, fmt.Errorf("cue: definition %s not found in schema", definition)
+		}
+		value = def.Unify(value)
+	}
+
+	if err := value.Validate(cue.Concrete(true), cue.Final()); err != nil {
+		return nil, wrapError(err)
+	}
+	if value.IncompleteKind() != cue.StructKind {
+		return nil, errors.New("cue: top-level value must be a struct")
+	}
+	jsonData, err := value.MarshalJSON()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(jsonData); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// wrapError formats every CUE error with its positions
+func wrapError(err error) error {
+	return fmt.Errorf("cue: %s", strings.TrimSpace(cueerrors.Details(err, nil)))
+}
@@ -0,0 +1,75 @@
+package cue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gonfig/test"
+)
+
+// TestParse_Success tests successful evaluation of CUE data with constraints and defaults.
+func TestParse_Success(t *testing.T) {
+	data := []byte(`
+#Server: {
+	addr: string | *"0.0.0.0"
+	port: int & >0 & <65536
+}
+
+server: #Server & {port: 8080}
+hosts: ["a", "b"]
+replicas: len(hosts)
+`)
+	s, err := Cue{}.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"server":   map[string]any{"addr": "0.0.0.0", "port": float64(8080)},
+		"hosts":    []any{"a", "b"},
+		"replicas": float64(2),
+	}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+}
+
+// TestParse_InvalidCue tests error handling for CUE data that fails to evaluate.
+func TestParse_InvalidCue(t *testing.T) {
+	cases := map[string]string{
+		"syntax":     "server: {",
+		"conflict":   "port: int & >0\nport: -1",
+		"incomplete": "port: int",
+		"top-level":  `"value"`,
+	}
+	for name, data := range cases {
+		if _, err := (Cue{}).Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	_, err := Cue{}.WithSource("conf/app.cue").Parse([]byte("a: 1\nport: int & >0\nport: -1"))
+	if err == nil || !strings.Contains(err.Error(), "conf/app.cue:3") {
+		t.Errorf("expected error with the position in the source; got %v", err)
+	}
+}
+
+// TestParse_Schema tests that data is checked against a schema generated from a proto message.
+func TestParse_Schema(t *testing.T) {
+	formatter := Cue{Schema: Schema((&test.Config{}).ProtoReflect().Descriptor())}
+	s, err := formatter.Parse([]byte(`field1: "a"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"field1": "a"}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+
+	if _, err := formatter.Parse([]byte(`field1: 1`)); err == nil {
+		t.Error("expected error for mismatched type")
+	}
+	if _, err := formatter.Parse([]byte(`unknown: "a"`)); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
@@ -0,0 +1,140 @@
+package cue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema generates CUE constraints for the JSON mapping of a proto message.
+// The message is declared as DefaultDefinition and every message it references as a definition
+// named after its full name, so the result can be used as Cue.Schema directly.
+// Fields are optional and accept both their proto and JSON names; definitions are closed,
+// so unknown fields are rejected.
+//
+// Args:
+//
+//	desc (protoreflect.MessageDescriptor): Descriptor of the config message
+//
+// Returns:
+//
+//	string: CUE source of the schema
+func Schema(desc protoreflect.MessageDescriptor) string {
+	g := &schemaGenerator{seen: make(map[protoreflect.FullName]bool)}
+	g.buf.WriteString(DefaultDefinition + ": " + g.message(desc) + "\n")
+	for i := 0; i < len(g.queue); i++ {
+		g.definition(g.queue[i])
+	}
+	return g.buf.String()
+}
+
+// schemaGenerator accumulates the definitions of the referenced messages
+type schemaGenerator struct {
+	buf   strings.Builder
+	seen  map[protoreflect.FullName]bool
+	queue []protoreflect.MessageDescriptor
+}
+
+// message returns a reference to the definition of a message, queuing it if necessary
+func (g *schemaGenerator) message(desc protoreflect.MessageDescriptor) string {
+	if !g.seen[desc.FullName()] {
+		g.seen[desc.FullName()] = true
+		g.queue = append(g.queue, desc)
+	}
+	return definitionName(desc.FullName())
+}
+
+// definition writes the definition of a message
+func (g *schemaGenerator) definition(desc protoreflect.MessageDescriptor) {
+	fmt.Fprintf(&g.buf, "\n%s: {\n", definitionName(desc.FullName()))
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		typ := g.field(field)
+		fmt.Fprintf(&g.buf, "\t%s?: %s\n", strconv.Quote(field.TextName()), typ)
+		if field.JSONName() != field.TextName() {
+			fmt.Fprintf(&g.buf, "\t%s?: %s\n", strconv.Quote(field.JSONName()), typ)
+		}
+	}
+	g.buf.WriteString("}\n")
+}
+
+// field returns the constraint of a field
+func (g *schemaGenerator) field(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return "{[string]: " + g.singular(field.MapValue()) + "}"
+	case field.IsList():
+		return "[..." + g.singular(field) + "]"
+	default:
+		return g.singular(field)
+	}
+}
+
+// singular returns the constraint of a single value of a field
+func (g *schemaGenerator) singular(field protoreflect.FieldDescriptor) string {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "string"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "number"
+	case protoreflect.EnumKind:
+		return enum(field.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if typ, ok := wellKnownTypes[field.Message().FullName()]; ok {
+			return typ
+		}
+		return g.message(field.Message())
+	default:
+		return "_"
+	}
+}
+
+// enum returns the constraint of an enum, which accepts value names and numbers
+func enum(desc protoreflect.EnumDescriptor) string {
+	values := desc.Values()
+	alternatives := make([]string, 0, values.Len()+1)
+	for i := 0; i < values.Len(); i++ {
+		alternatives = append(alternatives, strconv.Quote(string(values.Get(i).Name())))
+	}
+	alternatives = append(alternatives, "int32")
+	return strings.Join(alternatives, " | ")
+}
+
+// definitionName returns the name of the definition of a message
+func definitionName(name protoreflect.FullName) string {
+	return "#" + strings.ReplaceAll(string(name), ".", "_")
+}
+
+// wellKnownTypes maps well-known types to the constraints of their JSON mapping
+var wellKnownTypes = map[protoreflect.FullName]string{
+	"google.protobuf.Any":         "{...}",
+	"google.protobuf.Struct":      "{...}",
+	"google.protobuf.Value":       "_",
+	"google.protobuf.ListValue":   "[...]",
+	"google.protobuf.Empty":       "{}",
+	"google.protobuf.Timestamp":   "string",
+	"google.protobuf.Duration":    "string",
+	"google.protobuf.FieldMask":   "string",
+	"google.protobuf.BoolValue":   "bool",
+	"google.protobuf.StringValue": "string",
+	"google.protobuf.BytesValue":  "string",
+	"google.protobuf.Int32Value":  "int32",
+	"google.protobuf.UInt32Value": "uint32",
+	"google.protobuf.Int64Value":  "int64",
+	"google.protobuf.UInt64Value": "uint64",
+	"google.protobuf.FloatValue":  "number",
+	"google.protobuf.DoubleValue": "number",
+}
@@ -1,8 +1,14 @@
 package env
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/joho/godotenv"
 	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -12,7 +18,26 @@ func init() {
 }
 
 // Env implements the Formatter interface for environment variables format.
-type Env struct{}
+//
+// By default every variable becomes a top-level field named exactly like the variable.
+// Setting Separator or Message enables the key mapping, which turns
+// APP_REDIS__ADDR=localhost into {"redis": {"addr": "localhost"}} for the prefix "APP_" and separator "__":
+//   - the prefix is stripped and variables without it are ignored
+//   - the rest of the name is split into nested fields at the separator
+//   - names are case-folded to the lower-case proto field names
+//   - numeric segments are list indices, e.g. APP_SERVERS__0__HOST
+//
+// With Message, segments are matched case-insensitively against the proto and JSON names of the
+// message's fields. Consecutive segments are joined with "_" to match a field, so "_" can be used as
+// the separator even though field names contain underscores, and boolean fields get boolean values.
+type Env struct {
+	// Prefix is stripped from the variable names, variables without it are ignored
+	Prefix string
+	// Separator splits variable names into nested fields, e.g. "__" or "_"
+	Separator string
+	// Message is the descriptor of the config message the variable names are resolved against
+	Message protoreflect.MessageDescriptor
+}
 
 // Parse converts environment variables format data into a protobuf Struct.
 // The input data is expected to be a sequence of KEY=VALUE lines separated by newlines.
@@ -23,15 +48,235 @@ type Env struct{}
 //
 // Returns:
 // - *structpb.Struct: Parsed structured data with string values
-// - error: Error if parsing fails
-func (Env) Parse(data []byte) (*structpb.Struct, error) {
+// - error: Error if parsing fails or mapped keys conflict
+func (e Env) Parse(data []byte) (*structpb.Struct, error) {
 	m, err := godotenv.UnmarshalBytes(data)
 	if err != nil {
 		return nil, err
 	}
-	v := make(map[string]any)
-	for key, value := range m {
-		v[key] = value
+	if e.Separator == "" && e.Message == nil {
+		v := make(map[string]any)
+		for key, value := range m {
+			name, ok := strings.CutPrefix(key, e.Prefix)
+			if !ok || name == "" {
+				continue
+			}
+			v[name] = value
+		}
+		return structpb.NewStruct(v)
+	}
+
+	// Sort keys so that conflicts are reported deterministically
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	root := &node{}
+	for _, key := range keys {
+		name, ok := strings.CutPrefix(key, e.Prefix)
+		if !ok || name == "" {
+			continue
+		}
+		path, kind := e.resolve(name)
+		value, err := convert(key, m[key], kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := root.set(key, path, value); err != nil {
+			return nil, err
+		}
+	}
+	v, err := root.build("")
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := v.(map[string]any)
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	return structpb.NewStruct(fields)
+}
+
+// segment is a step in the path of a variable, either a field name or a list index
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// resolve maps a variable name without prefix to its path,
+// kind is the kind of the proto field the value is assigned to, or 0 if unknown
+func (e Env) resolve(name string) ([]segment, protoreflect.Kind) {
+	var parts []string
+	if e.Separator == "" {
+		parts = []string{name}
+	} else {
+		parts = strings.Split(name, e.Separator)
+	}
+
+	var path []segment
+	desc := e.Message
+	var kind protoreflect.Kind
+	for i := 0; i < len(parts); {
+		kind = 0
+		if desc == nil {
+			// Unknown fields are mapped generically
+			path = append(path, genericSegment(parts[i]))
+			i++
+			continue
+		}
+		field, n := findField(desc, parts[i:])
+		if field == nil {
+			desc = nil
+			continue
+		}
+		path = append(path, segment{key: field.TextName()})
+		i += n
+		desc, kind = nil, field.Kind()
+		switch {
+		case field.IsMap():
+			if i < len(parts) {
+				path = append(path, segment{key: strings.ToLower(parts[i])})
+				i++
+			}
+			kind = field.MapValue().Kind()
+			if kind == protoreflect.MessageKind {
+				desc = field.MapValue().Message()
+			}
+		case field.IsList():
+			if i < len(parts) {
+				if index, err := strconv.Atoi(parts[i]); err == nil && index >= 0 {
+					path = append(path, segment{index: index, isIndex: true})
+					i++
+				}
+			}
+			if kind == protoreflect.MessageKind {
+				desc = field.Message()
+			}
+		case kind == protoreflect.MessageKind || kind == protoreflect.GroupKind:
+			desc = field.Message()
+		}
+	}
+	return path, kind
+}
+
+// findField finds the field matching the longest run of leading parts joined with "_"
+func findField(desc protoreflect.MessageDescriptor, parts []string) (protoreflect.FieldDescriptor, int) {
+	fields := desc.Fields()
+	for n := len(parts); n > 0; n-- {
+		name := strings.Join(parts[:n], "_")
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if strings.EqualFold(field.TextName(), name) || strings.EqualFold(field.JSONName(), name) {
+				return field, n
+			}
+		}
+	}
+	return nil, 0
+}
+
+// genericSegment maps a part of a variable name to a list index if numeric, or a lower-case field name
+func genericSegment(part string) segment {
+	if index, err := strconv.Atoi(part); err == nil && index >= 0 {
+		return segment{index: index, isIndex: true}
+	}
+	return segment{key: strings.ToLower(part)}
+}
+
+// convert converts the value of a variable to the type of the proto field it is assigned to
+func convert(key string, value string, kind protoreflect.Kind) (any, error) {
+	if kind != protoreflect.BoolKind {
+		return value, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: invalid boolean %q", key, value)
+	}
+	return b, nil
+}
+
+// node is a value, object or list in the tree built from the variables
+type node struct {
+	// key is the variable that set the value
+	key    string
+	value  any
+	fields map[string]*node
+	items  map[int]*node
+}
+
+// set assigns a value at a path below the node
+func (n *node) set(key string, path []segment, value any) error {
+	for _, s := range path {
+		if n.key != "" {
+			return fmt.Errorf("env: %s conflicts with %s", key, n.key)
+		}
+		var child *node
+		if s.isIndex {
+			if n.fields != nil {
+				return fmt.Errorf("env: %s uses a list index where an object is expected", key)
+			}
+			if n.items == nil {
+				n.items = make(map[int]*node)
+			}
+			if child = n.items[s.index]; child == nil {
+				child = &node{}
+				n.items[s.index] = child
+			}
+		} else {
+			if n.items != nil {
+				return fmt.Errorf("env: %s uses a field where a list is expected", key)
+			}
+			if n.fields == nil {
+				n.fields = make(map[string]*node)
+			}
+			if child = n.fields[s.key]; child == nil {
+				child = &node{}
+				n.fields[s.key] = child
+			}
+		}
+		n = child
+	}
+	if n.key != "" || n.fields != nil || n.items != nil {
+		return fmt.Errorf("env: %s conflicts with another variable", key)
+	}
+	n.key, n.value = key, value
+	return nil
+}
+
+// build converts the node into a value accepted by structpb.NewValue
+func (n *node) build(path string) (any, error) {
+	switch {
+	case n.key != "":
+		return n.value, nil
+	case n.items != nil:
+		list := make([]any, len(n.items))
+		for i := range list {
+			item, ok := n.items[i]
+			if !ok {
+				return nil, fmt.Errorf("env: list %s is missing index %d", path, i)
+			}
+			value, err := item.build(path + "." + strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	default:
+		fields := make(map[string]any, len(n.fields))
+		for key, child := range n.fields {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			value, err := child.build(childPath)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = value
+		}
+		return fields, nil
 	}
-	return structpb.NewStruct(v)
 }
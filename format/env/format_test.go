@@ -3,6 +3,8 @@ package env
 import (
 	"reflect"
 	"testing"
+
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 // TestParse_Success tests successful parsing of valid environment variables format.
@@ -162,3 +164,65 @@ func TestParse_EmptyValue(t *testing.T) {
 		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
 	}
 }
+
+// TestParse_KeyMapping tests nesting of variables with a prefix and separator.
+func TestParse_KeyMapping(t *testing.T) {
+	data := []byte("APP_REDIS__ADDR=localhost:6379\nAPP_REDIS__MAX_CONNS=10\nAPP_SERVERS__1__HOST=b\nAPP_SERVERS__0__HOST=a\nOTHER=ignored")
+	parser := Env{Prefix: "APP_", Separator: "__"}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"redis": map[string]interface{}{"addr": "localhost:6379", "max_conns": "10"},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a"},
+			map[string]interface{}{"host": "b"},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_KeyMappingMessage tests resolution of variables against the fields of a message.
+func TestParse_KeyMappingMessage(t *testing.T) {
+	data := []byte("APP_OPTIONS_JAVA_MULTIPLE_FILES=true\nAPP_OPTIONS_GOPACKAGE=example\nAPP_MESSAGE_TYPE_0_NAME=Config\nAPP_DEPENDENCY_0=a.proto\nAPP_UNKNOWN_KEY=value")
+	parser := Env{Prefix: "APP_", Separator: "_", Message: (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"options":      map[string]interface{}{"java_multiple_files": true, "go_package": "example"},
+		"message_type": []interface{}{map[string]interface{}{"name": "Config"}},
+		"dependency":   []interface{}{"a.proto"},
+		"unknown":      map[string]interface{}{"key": "value"},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_InvalidKeyMapping tests error handling for variables that cannot be mapped.
+func TestParse_InvalidKeyMapping(t *testing.T) {
+	cases := map[string]string{
+		"conflict":      "APP_A=1\nAPP_A__B=2",
+		"missing index": "APP_LIST__1=a",
+		"mixed":         "APP_A__0=1\nAPP_A__B=2",
+	}
+	for name, data := range cases {
+		if _, err := (Env{Prefix: "APP_", Separator: "__"}).Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	message := (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()
+	if _, err := (Env{Separator: "_", Message: message}).Parse([]byte("OPTIONS_JAVA_MULTIPLE_FILES=maybe")); err == nil {
+		t.Error("expected error for invalid boolean")
+	}
+}
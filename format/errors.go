@@ -0,0 +1,400 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// protojsonPosition matches the position protojson adds to its errors, e.g. "(line 1:22)",
+// protobuf randomly separates it with a non-breaking space to discourage matching error messages exactly
+var protojsonPosition = regexp.MustCompile(`(?::[ \x{00a0}]|[ \x{00a0}])\(line (\d+):(\d+)\)`)
+
+// Position is a location in a document, lines and columns start at 1
+type Position struct {
+	// Line is the line number, 0 if unknown
+	Line int
+	// Column is the column number in characters, 0 if unknown
+	Column int
+}
+
+// String formats the position as "line:column", omitting unknown parts
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return ""
+	case p.Column == 0:
+		return strconv.Itoa(p.Line)
+	default:
+		return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	}
+}
+
+// PositionOf returns the position of a byte offset in data, counting columns in characters
+//
+// Args:
+//
+//	data ([]byte): Document
+//	offset (int): Byte offset in the document
+//
+// Returns:
+//
+//	Position: Position of the offset
+func PositionOf(data []byte, offset int) Position {
+	offset = min(max(offset, 0), len(data))
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return Position{
+		Line:   1 + bytes.Count(data[:lineStart], []byte("\n")),
+		Column: 1 + utf8.RuneCount(data[lineStart:offset]),
+	}
+}
+
+// Locator is implemented by formatters able to find fields in the data they parse,
+// so that errors converting the parsed data into a message point at the original document.
+type Locator interface {
+	// Locate returns the position of the field at path in data
+	//
+	// Args:
+	//   data ([]byte): Raw configuration data
+	//   path (string): Field path, e.g. "redis.port" or "routes[0].path"
+	//
+	// Returns:
+	//   Position: Position of the field
+	//   bool: Whether the field was found
+	Locate(data []byte, path string) (Position, bool)
+}
+
+// ParseError reports data that cannot be parsed.
+// Formatters set the position when the underlying parser reports it, resources set the source.
+//
+//	config.yaml:3:5: yaml: mapping values are not allowed in this context
+type ParseError struct {
+	// Source identifies the data, e.g. the path of a file or a Consul key, empty if unknown
+	Source string
+	// Position is the location of the error in the data, zero if unknown
+	Position
+	// Path is the path of the field, empty if unknown
+	Path string
+	// Err is the error of the parser
+	Err error
+}
+
+// Error formats the error as "source:line:column: field path: message"
+func (e *ParseError) Error() string {
+	return errorLocation(e.Source, e.Position, e.Path) + errorMessage(e.Err)
+}
+
+// Unwrap returns the error of the parser
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ConvertError reports parsed data that cannot be converted into a message,
+// e.g. a string for an integer field or an unknown field.
+// The position refers to the original document when the formatter implements Locator.
+//
+//	config.yaml:4:9: field redis.port: proto: invalid value for int32 field port: "abc"
+type ConvertError struct {
+	// Source identifies the data providing the field, empty if unknown
+	Source string
+	// Position is the location of the field in the data, zero if unknown
+	Position
+	// Path is the path of the field, e.g. "redis.port", empty if unknown
+	Path string
+	// Err is the error of the conversion
+	Err error
+}
+
+// Error formats the error as "source:line:column: field path: message"
+func (e *ConvertError) Error() string {
+	return errorLocation(e.Source, e.Position, e.Path) + errorMessage(e.Err)
+}
+
+// Unwrap returns the error of the conversion
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
+
+// errorLocation formats the location prefix of ParseError and ConvertError
+func errorLocation(source string, position Position, path string) string {
+	var location []string
+	if source != "" {
+		location = append(location, source)
+	}
+	if position.Line > 0 {
+		location = append(location, position.String())
+	}
+	prefix := strings.Join(location, ":")
+	if prefix != "" {
+		prefix += ": "
+	}
+	if path != "" {
+		prefix += "field " + path + ": "
+	}
+	return prefix
+}
+
+// errorMessage returns the message of an error without the positions added by protojson,
+// which are reported by ParseError and ConvertError instead
+func errorMessage(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return protojsonPosition.ReplaceAllString(err.Error(), "")
+}
+
+// ProtojsonPosition returns the position protojson reports in an error, e.g. "(line 3:8)"
+//
+// Args:
+//
+//	err (error): Error of protojson or of the JSON methods of well-known types
+//
+// Returns:
+//
+//	Position: Position of the error
+//	bool: Whether the error has a position
+func ProtojsonPosition(err error) (Position, bool) {
+	match := protojsonPosition.FindStringSubmatch(err.Error())
+	if match == nil {
+		return Position{}, false
+	}
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+	return Position{Line: line, Column: column}, true
+}
+
+// StructToMessage converts a Struct into a message using the protojson mapping.
+// As protojson accepts strings for numeric fields, the strings "true" and "false" (case-insensitive) are accepted
+// for bool fields, so that formats without types such as Properties and XML keep every value a string.
+// Errors are ConvertErrors with the path of the offending field, see Sensitive.Redact to hide sensitive values.
+//
+// Args:
+//
+//	value (*structpb.Struct): Parsed configuration data
+//	message (proto.Message): Message the data is converted into
+//
+// Returns:
+//
+//	error: *ConvertError if the data does not match the message
+func StructToMessage(value *structpb.Struct, message proto.Message) error {
+	encoder := &spanEncoder{}
+	if err := encoder.encodeStruct("", value, messageFields(message.ProtoReflect().Descriptor())); err != nil {
+		return &ConvertError{Err: err}
+	}
+	err := protojson.Unmarshal(encoder.buf.Bytes(), message)
+	if err == nil {
+		return nil
+	}
+	convertErr := &ConvertError{Err: err}
+	// The encoded data is a single line, so the column is the character offset of the error
+	if position, ok := ProtojsonPosition(err); ok && position.Line == 1 {
+		convertErr.Path = encoder.path(position.Column - 1)
+	}
+	return convertErr
+}
+
+// span is the range of characters of a field in encoded JSON
+type span struct {
+	start int
+	end   int
+	path  string
+}
+
+// spanEncoder encodes Struct values as single-line JSON, recording the span of every field
+type spanEncoder struct {
+	buf bytes.Buffer
+	// chars is the number of characters written
+	chars int
+	spans []span
+}
+
+// write appends data to the buffer
+func (e *spanEncoder) write(data []byte) {
+	e.buf.Write(data)
+	e.chars += utf8.RuneCount(data)
+}
+
+// path returns the path of the innermost field containing the character at offset
+func (e *spanEncoder) path(offset int) string {
+	path := ""
+	for _, s := range e.spans {
+		// Spans are recorded before their children, later matches are nested
+		if s.start <= offset && offset < s.end {
+			path = s.path
+		}
+	}
+	return path
+}
+
+// encodeStruct encodes a Struct value, fields resolves the proto field of a key and is nil for untyped values
+func (e *spanEncoder) encodeStruct(path string, value *structpb.Struct, fields func(key string) protoreflect.FieldDescriptor) error {
+	e.write([]byte("{"))
+	for i, key := range sortedKeys(value.GetFields()) {
+		if i > 0 {
+			e.write([]byte(","))
+		}
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		index := len(e.spans)
+		e.spans = append(e.spans, span{start: e.chars, path: fieldPath})
+		name, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		e.write(name)
+		e.write([]byte(":"))
+		var field protoreflect.FieldDescriptor
+		if fields != nil {
+			field = fields(key)
+		}
+		if err := e.encodeValue(fieldPath, value.GetFields()[key], field, false); err != nil {
+			return err
+		}
+		e.spans[index].end = e.chars
+	}
+	e.write([]byte("}"))
+	return nil
+}
+
+// encodeValue encodes a Value assigned to a proto field, nil if unknown.
+// element is set for the items of a repeated field.
+func (e *spanEncoder) encodeValue(path string, value *structpb.Value, field protoreflect.FieldDescriptor, element bool) error {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StructValue:
+		var fields func(key string) protoreflect.FieldDescriptor
+		switch {
+		case field == nil || field.IsList() && !element:
+		case field.IsMap():
+			fields = func(string) protoreflect.FieldDescriptor { return field.MapValue() }
+		case field.Message() != nil:
+			fields = messageFields(field.Message())
+		}
+		return e.encodeStruct(path, kind.StructValue, fields)
+	case *structpb.Value_ListValue:
+		if field != nil && (!field.IsList() || element) {
+			field = nil
+		}
+		e.write([]byte("["))
+		for i, item := range kind.ListValue.GetValues() {
+			if i > 0 {
+				e.write([]byte(","))
+			}
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			index := len(e.spans)
+			e.spans = append(e.spans, span{start: e.chars, path: itemPath})
+			if err := e.encodeValue(itemPath, item, field, true); err != nil {
+				return err
+			}
+			e.spans[index].end = e.chars
+		}
+		e.write([]byte("]"))
+		return nil
+	case *structpb.Value_StringValue:
+		if field != nil && (!field.IsList() || element) && isBool(field) {
+			if b, ok := parseBool(kind.StringValue); ok {
+				e.write([]byte(strconv.FormatBool(b)))
+				return nil
+			}
+		}
+		data, err := json.Marshal(kind.StringValue)
+		if err != nil {
+			return err
+		}
+		e.write(data)
+	case *structpb.Value_NumberValue:
+		data, err := json.Marshal(kind.NumberValue)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+		e.write(data)
+	case *structpb.Value_BoolValue:
+		e.write([]byte(strconv.FormatBool(kind.BoolValue)))
+	default:
+		e.write([]byte("null"))
+	}
+	return nil
+}
+
+// messageFields resolves the fields of a message by JSON or proto name, as protojson does.
+// It is nil for the well-known types taking untyped objects, google.protobuf.Struct, Value and Any.
+func messageFields(message protoreflect.MessageDescriptor) func(key string) protoreflect.FieldDescriptor {
+	switch message.FullName() {
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.Any":
+		return nil
+	}
+	return func(key string) protoreflect.FieldDescriptor {
+		if field := message.Fields().ByJSONName(key); field != nil {
+			return field
+		}
+		return message.Fields().ByTextName(key)
+	}
+}
+
+// isBool reports whether a field takes a boolean, including the google.protobuf.BoolValue wrapper
+func isBool(field protoreflect.FieldDescriptor) bool {
+	if field.IsMap() {
+		return false
+	}
+	return field.Kind() == protoreflect.BoolKind ||
+		field.Message() != nil && field.Message().FullName() == "google.protobuf.BoolValue"
+}
+
+// parseBool parses the strings "true" and "false", case-insensitively
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+// sortedKeys returns the keys of the fields in lexical order, so that errors are deterministic
+func sortedKeys(fields map[string]*structpb.Value) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// SplitPath splits a field path into keys and list indexes, e.g. "routes[0].path" becomes "routes", 0 and "path"
+//
+// Args:
+//
+//	path (string): Field path
+//
+// Returns:
+//
+//	[]any: Keys as strings and list indexes as ints, nil if the path is malformed
+func SplitPath(path string) []any {
+	var segments []any
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		segments = append(segments, key)
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(index)
+			if !ok || err != nil {
+				return nil
+			}
+			segments = append(segments, n)
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return segments
+}
@@ -0,0 +1,172 @@
+package format_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestStructToMessage tests that conversion errors name the path of the offending field.
+func TestStructToMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		value map[string]any
+		path  string
+	}{
+		{"Invalid Value", map[string]any{"name": "Alice", "field": []any{map[string]any{"name": "id", "number": "abc"}}}, "field[0].number"},
+		{"Unknown Field", map[string]any{"name": "Alice", "options": map[string]any{"unknown": true}}, "options.unknown"},
+		{"Invalid Enum", map[string]any{"field": []any{map[string]any{"name": "日本", "type": "TYPE_MISSING"}}}, "field[0].type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := structpb.NewStruct(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = format.StructToMessage(value, &descriptorpb.DescriptorProto{})
+			var convertErr *format.ConvertError
+			if !errors.As(err, &convertErr) {
+				t.Fatalf("expected *format.ConvertError; got %v", err)
+			}
+			if convertErr.Path != tt.path {
+				t.Errorf("expected path %q; got %q", tt.path, convertErr.Path)
+			}
+			if strings.Contains(err.Error(), "(line") || !strings.HasPrefix(err.Error(), "field "+tt.path+": ") {
+				t.Errorf("expected error naming the field without JSON positions; got %v", err)
+			}
+		})
+	}
+
+	value, err := structpb.NewStruct(map[string]any{"name": "Alice", "field": []any{map[string]any{"number": 8080}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &descriptorpb.DescriptorProto{}
+	if err := format.StructToMessage(value, message); err != nil {
+		t.Fatal(err)
+	}
+	if message.GetName() != "Alice" || message.GetField()[0].GetNumber() != 8080 {
+		t.Errorf("unexpected message %v", message)
+	}
+}
+
+// TestStructToMessage_Bool tests that the strings "true" and "false" are accepted for bool fields only.
+func TestStructToMessage_Bool(t *testing.T) {
+	value, err := structpb.NewStruct(map[string]any{
+		"file": []any{map[string]any{"name": "true", "options": map[string]any{"deprecated": "True", "java_multiple_files": "false"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &descriptorpb.FileDescriptorSet{}
+	if err := format.StructToMessage(value, message); err != nil {
+		t.Fatal(err)
+	}
+	file := message.GetFile()[0]
+	if file.GetName() != "true" || !file.GetOptions().GetDeprecated() || file.GetOptions().JavaMultipleFiles == nil {
+		t.Errorf("unexpected message %v", message)
+	}
+
+	value, err = structpb.NewStruct(map[string]any{"options": map[string]any{"deprecated": "yes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = format.StructToMessage(value, &descriptorpb.FileDescriptorProto{})
+	var convertErr *format.ConvertError
+	if !errors.As(err, &convertErr) || convertErr.Path != "options.deprecated" {
+		t.Errorf("expected conversion error of field options.deprecated; got %v", err)
+	}
+}
+
+// TestSensitive_Redact tests that conversion errors redact the values marked as sensitive only.
+func TestSensitive_Redact(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     map[string]any
+		sensitive bool
+		expected  string
+	}{
+		{"Sensitive String", map[string]any{"optimizeFor": "s3cret-SPEED"}, true, `invalid value for enum field optimizeFor: [redacted]`},
+		{"Sensitive Number", map[string]any{"optimizeFor": 42.5}, true, `invalid value for enum field optimizeFor: [redacted]`},
+		{"Other String", map[string]any{"optimizeFor": "not-SPEED"}, false, `invalid value for enum field optimizeFor: "not-SPEED"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := structpb.NewStruct(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sensitive := &format.Sensitive{}
+			if tt.sensitive {
+				sensitive.Mark("optimizeFor", value.GetFields()["optimizeFor"])
+			}
+			err = sensitive.Redact(format.StructToMessage(value, &descriptorpb.FileOptions{}))
+			if err == nil || !strings.HasSuffix(err.Error(), tt.expected) {
+				t.Errorf("expected error ending with %q; got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// TestMarkSensitive tests that values are marked in every set of the context, replacing older values of the path.
+func TestMarkSensitive(t *testing.T) {
+	outer, inner := &format.Sensitive{}, &format.Sensitive{}
+	ctx := format.ContextWithSensitive(format.ContextWithSensitive(context.Background(), outer), inner)
+	format.MarkSensitive(ctx, "optimizeFor", structpb.NewStringValue("old-SPEED"))
+	format.MarkSensitive(ctx, "optimizeFor", structpb.NewStringValue("new-SPEED"))
+	// Without a set in the context, values are not marked
+	format.MarkSensitive(context.Background(), "optimizeFor", structpb.NewStringValue("other-SPEED"))
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"new-SPEED", `invalid value for enum field optimizeFor: [redacted]`},
+		{"old-SPEED", `invalid value for enum field optimizeFor: "old-SPEED"`},
+	}
+	for _, sensitive := range []*format.Sensitive{outer, inner} {
+		for _, tt := range tests {
+			value := &structpb.Struct{Fields: map[string]*structpb.Value{"optimizeFor": structpb.NewStringValue(tt.value)}}
+			err := sensitive.Redact(format.StructToMessage(value, &descriptorpb.FileOptions{}))
+			if err == nil || !strings.HasSuffix(err.Error(), tt.expected) {
+				t.Errorf("expected error ending with %q; got %v", tt.expected, err)
+			}
+		}
+	}
+	var sensitive *format.Sensitive
+	value := &structpb.Struct{Fields: map[string]*structpb.Value{"optimizeFor": structpb.NewStringValue("new-SPEED")}}
+	if err := sensitive.Redact(format.StructToMessage(value, &descriptorpb.FileOptions{})); err == nil || strings.Contains(err.Error(), "[redacted]") {
+		t.Errorf("expected unredacted error for nil set; got %v", err)
+	}
+}
+
+// TestParseError_Error tests the formatting of parse and conversion errors.
+func TestParseError_Error(t *testing.T) {
+	cause := errors.New("yaml: mapping values are not allowed in this context")
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{&format.ParseError{Source: "config.yaml", Position: format.Position{Line: 3, Column: 5}, Err: cause}, "config.yaml:3:5: yaml: mapping values are not allowed in this context"},
+		{&format.ParseError{Source: "config.yaml", Position: format.Position{Line: 3}, Err: cause}, "config.yaml:3: yaml: mapping values are not allowed in this context"},
+		{&format.ParseError{Err: cause}, "yaml: mapping values are not allowed in this context"},
+		{&format.ConvertError{Source: "config.yaml", Position: format.Position{Line: 4, Column: 3}, Path: "redis.port",
+			Err: errors.New(`proto: (line 1:22): invalid value for int32 field port: "abc"`)},
+			`config.yaml:4:3: field redis.port: proto: invalid value for int32 field port: "abc"`},
+		{&format.ParseError{Source: "config.json", Position: format.Position{Line: 3, Column: 8}, Err: errors.New("proto: syntax error (line 3:8): unexpected token }")},
+			"config.json:3:8: proto: syntax error: unexpected token }"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("expected %q; got %q", tt.expected, got)
+		}
+		if !errors.Is(tt.err, cause) && strings.HasPrefix(tt.expected, "config.yaml:3") {
+			t.Errorf("expected the error to wrap its cause")
+		}
+	}
+}
@@ -1,20 +1,11 @@
 package format
 
 import (
-	"strings"
-	"sync"
-
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
-// Global formatters registry mapping file extensions to their corresponding parsers
-var (
-	// formatters stores registered format parsers
-	formatters = make(map[string]Formatter)
-	// mutex to protect concurrent access to formatters
-	mutex sync.RWMutex
-)
-
 // Formatter interface defines the standard method for parsing configuration data
 type Formatter interface {
 	// Parse converts byte data into a protobuf Struct object
@@ -28,19 +19,65 @@ type Formatter interface {
 	Parse(data []byte) (*structpb.Struct, error)
 }
 
-// RegisterFormatter associates a file extension with a configuration parser
+// MessageFormatter is implemented by formatters that decode data directly into a typed message
+// with full type fidelity, such as the protobuf text and wire formats.
+// Resources implementing resource.MessageResource use it instead of Parse when the message type is known.
+type MessageFormatter interface {
+	Formatter
+	// Unmarshal decodes data into a message
+	//
+	// Args:
+	//   data ([]byte): Raw configuration data
+	//   message (proto.Message): Message the data is decoded into, it is reset first
+	//
+	// Returns:
+	//   error: Error if decoding fails
+	Unmarshal(data []byte, message proto.Message) error
+}
+
+// SourceFormatter is implemented by formatters whose result depends on the location of the data,
+// such as formats resolving relative imports against the directory of the file being parsed.
+// Resources that know the location of their data call WithSource to obtain the formatter they use.
+type SourceFormatter interface {
+	Formatter
+	// WithSource returns a formatter parsing data read from the named source
+	//
+	// Args:
+	//   name (string): Location of the data, e.g. the path of a file
+	//
+	// Returns:
+	//   Formatter: Formatter bound to the source
+	WithSource(name string) Formatter
+}
+
+// DependentFormatter is implemented by formatters that read other files while parsing,
+// such as formats with import or include directives.
+// Resources watching for changes also watch these files.
+type DependentFormatter interface {
+	Formatter
+	// Dependencies returns the paths of the files read by previous Parse calls
+	//
+	// Returns:
+	//   []string: Paths of the files the parsed data depends on
+	Dependencies() []string
+}
+
+// RegisterFormatter associates a file extension with a configuration parser in DefaultRegistry
 //
 // Args:
 //
 //	ext (string): File extension (e.g., "yaml", "toml")
 //	formatter (Formatter): Implementation of the Formatter interface
-func RegisterFormatter(ext string, formatter Formatter) {
-	mutex.Lock()
-	formatters[strings.ToLower(ext)] = formatter
-	mutex.Unlock()
+//	opts (...Option): Options applied to the formatter, e.g. WithStrict
+//
+// Returns:
+//
+//	error: Error if an option does not apply to the formatter, nothing is registered then
+func RegisterFormatter(ext string, formatter Formatter, opts ...Option) error {
+	return DefaultRegistry.Register(ext, formatter, opts...)
 }
 
-// GetFormatter retrieves the parser associated with a specific file extension
+// GetFormatter retrieves the parser associated with a specific file extension in DefaultRegistry
 //
 // Args:
 //
@@ -50,8 +87,28 @@ func RegisterFormatter(ext string, formatter Formatter) {
 //
 //	Formatter: Registered parser or nil if not found
 func GetFormatter(ext string) (Formatter, bool) {
-	mutex.RLock()
-	formatter, ok := formatters[strings.ToLower(ext)]
-	mutex.RUnlock()
-	return formatter, ok
+	return DefaultRegistry.Get(ext)
+}
+
+// StructFromMessage converts a message into a Struct using the protojson mapping.
+// Converting the Struct back with protojson yields the same message, e.g. 64-bit integers are kept as strings.
+//
+// Args:
+//
+//	message (proto.Message): Message to convert
+//
+// Returns:
+//
+//	*structpb.Struct: Struct representing the message
+//	error: Error if the conversion fails
+func StructFromMessage(message proto.Message) (*structpb.Struct, error) {
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
@@ -0,0 +1,193 @@
+package hcl
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/soyacen/gonfig/format"
+	"github.com/zclconf/go-cty/cty"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Hcl formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("hcl", Hcl{})
+}
+
+// Hcl implements the Formatter interface for HCL2 native syntax.
+//
+// HCL bodies are converted with the following rules:
+//   - An attribute becomes a key holding the value of its expression. Expressions are evaluated
+//     without variables or functions, so they must be static (literals, templates without
+//     interpolation, tuples, objects and operators on them).
+//   - A block without labels becomes a key named after the block type holding the block body
+//     as an object, e.g. `server { port = 80 }` becomes {"server": {"port": 80}}.
+//   - A block with labels becomes nested objects keyed by the block type and then by every
+//     label, e.g. `service "http" "web" { port = 80 }` becomes {"service": {"http": {"web": {"port": 80}}}}.
+//   - Blocks repeated with the same type and labels become a list of objects in source order,
+//     e.g. two `listener { ... }` blocks become {"listener": [{...}, {...}]}.
+//   - With BlockLists, blocks always become a list, even a single one, e.g. a single
+//     `listener { ... }` block becomes {"listener": [{...}]} and `service "http" "web" { ... }`
+//     becomes {"service": {"http": {"web": [{...}]}}}.
+//   - All blocks of a type must have the same number of labels and a block type must not
+//     reuse the name of an attribute of the same body.
+//
+// By default the shape of a block depends on the number of blocks, which suits singular message fields,
+// e.g. `server { ... }` for a field server. Blocks of repeated message fields may appear once or more,
+// so use BlockLists for them, e.g. format.RegisterFormatter("hcl", hcl.Hcl{BlockLists: true}).
+type Hcl struct {
+	// BlockLists makes every block a list of objects, even if it is not repeated
+	BlockLists bool
+}
+
+// Parse converts HCL-formatted byte data into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The HCL-formatted byte slice to be parsed
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if parsing fails (e.g., invalid HCL syntax or non-static expressions)
+func (h Hcl) Parse(data []byte) (*structpb.Struct, error) {
+	file, diags := hclsyntax.ParseConfig(data, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("hcl: unexpected body type %T", file.Body)
+	}
+	v, err := h.convertBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(v)
+}
+
+// convertBody converts the attributes and blocks of a body into a map
+func (h Hcl) convertBody(body *hclsyntax.Body) (map[string]any, error) {
+	v := make(map[string]any, len(body.Attributes)+len(body.Blocks))
+	for name, attr := range body.Attributes {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		goValue, err := convertValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("hcl: attribute %q: %w", name, err)
+		}
+		v[name] = goValue
+	}
+
+	// Group blocks by type, keeping source order
+	var types []string
+	groups := make(map[string][]*hclsyntax.Block)
+	for _, block := range body.Blocks {
+		if _, ok := body.Attributes[block.Type]; ok {
+			return nil, fmt.Errorf("hcl: %s: block %q conflicts with attribute of the same name", block.DefRange(), block.Type)
+		}
+		if _, ok := groups[block.Type]; !ok {
+			types = append(types, block.Type)
+		}
+		groups[block.Type] = append(groups[block.Type], block)
+	}
+
+	for _, blockType := range types {
+		blocks := groups[blockType]
+		labels := len(blocks[0].Labels)
+		tree := make(map[string]any)
+		for _, block := range blocks {
+			if len(block.Labels) != labels {
+				return nil, fmt.Errorf("hcl: %s: block %q has %d labels, expected %d", block.DefRange(), block.Type, len(block.Labels), labels)
+			}
+			obj, err := h.convertBody(block.Body)
+			if err != nil {
+				return nil, err
+			}
+			// Walk down the labels, collecting repeated blocks at the leaf
+			node := tree
+			path := append([]string{block.Type}, block.Labels...)
+			for _, key := range path[:len(path)-1] {
+				child, ok := node[key].(map[string]any)
+				if !ok {
+					child = make(map[string]any)
+					node[key] = child
+				}
+				node = child
+			}
+			leaf := path[len(path)-1]
+			items, _ := node[leaf].([]any)
+			node[leaf] = append(items, obj)
+		}
+		if h.BlockLists {
+			v[blockType] = tree[blockType]
+			continue
+		}
+		v[blockType] = collapse(tree[blockType], labels)
+	}
+	return v, nil
+}
+
+// collapse replaces leaf lists holding a single block with the block itself
+func collapse(node any, depth int) any {
+	if depth == 0 {
+		items := node.([]any)
+		if len(items) == 1 {
+			return items[0]
+		}
+		return items
+	}
+	m := node.(map[string]any)
+	for key, child := range m {
+		m[key] = collapse(child, depth-1)
+	}
+	return m
+}
+
+// convertValue converts a cty value into a value accepted by structpb.NewValue
+func convertValue(value cty.Value) (any, error) {
+	if value.IsNull() {
+		return nil, nil
+	}
+	if !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
+	value, _ = value.Unmark()
+	ty := value.Type()
+	switch {
+	case ty == cty.String:
+		return value.AsString(), nil
+	case ty == cty.Number:
+		f, _ := value.AsBigFloat().Float64()
+		return f, nil
+	case ty == cty.Bool:
+		return value.True(), nil
+	case ty.IsListType() || ty.IsTupleType() || ty.IsSetType():
+		list := make([]any, 0, value.LengthInt())
+		for it := value.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			goElem, err := convertValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, goElem)
+		}
+		return list, nil
+	case ty.IsMapType() || ty.IsObjectType():
+		m := make(map[string]any, value.LengthInt())
+		for it := value.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			goElem, err := convertValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[key.AsString()] = goElem
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", ty.FriendlyName())
+	}
+}
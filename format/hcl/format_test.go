@@ -0,0 +1,156 @@
+package hcl
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParse_Success tests successful parsing of attributes and blocks.
+func TestParse_Success(t *testing.T) {
+	data := []byte(`
+name    = "Alice"
+age     = 30
+enabled = true
+tags    = ["a", "b"]
+limits  = { cpu = 2, memory = "1Gi" }
+
+server {
+  addr = "0.0.0.0"
+  port = 8080
+}
+
+service "http" "web" {
+  port = 80
+}
+
+service "grpc" "api" {
+  port = 9090
+}
+
+listener {
+  port = 1
+}
+
+listener {
+  port = 2
+}
+`)
+	parser := Hcl{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"name":    "Alice",
+		"age":     float64(30),
+		"enabled": true,
+		"tags":    []interface{}{"a", "b"},
+		"limits": map[string]interface{}{
+			"cpu":    float64(2),
+			"memory": "1Gi",
+		},
+		"server": map[string]interface{}{
+			"addr": "0.0.0.0",
+			"port": float64(8080),
+		},
+		"service": map[string]interface{}{
+			"http": map[string]interface{}{
+				"web": map[string]interface{}{"port": float64(80)},
+			},
+			"grpc": map[string]interface{}{
+				"api": map[string]interface{}{"port": float64(9090)},
+			},
+		},
+		"listener": []interface{}{
+			map[string]interface{}{"port": float64(1)},
+			map[string]interface{}{"port": float64(2)},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_RepeatedLabeledBlocks tests that blocks repeated with the same labels become a list.
+func TestParse_RepeatedLabeledBlocks(t *testing.T) {
+	data := []byte(`
+route "api" { path = "/a" }
+route "api" { path = "/b" }
+`)
+	parser := Hcl{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"route": map[string]interface{}{
+			"api": []interface{}{
+				map[string]interface{}{"path": "/a"},
+				map[string]interface{}{"path": "/b"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_BlockLists tests that blocks become lists regardless of their number with BlockLists.
+func TestParse_BlockLists(t *testing.T) {
+	data := []byte(`
+listener { port = 80 }
+route "api" { path = "/a" }
+route "web" { path = "/" }
+route "web" { path = "/b" }
+`)
+	parser := Hcl{BlockLists: true}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"listener": []interface{}{
+			map[string]interface{}{"port": float64(80)},
+		},
+		"route": map[string]interface{}{
+			"api": []interface{}{
+				map[string]interface{}{"path": "/a"},
+			},
+			"web": []interface{}{
+				map[string]interface{}{"path": "/"},
+				map[string]interface{}{"path": "/b"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_InvalidHCL tests error handling for invalid and ambiguous HCL.
+func TestParse_InvalidHCL(t *testing.T) {
+	tests := map[string]string{
+		"syntax":         "name = ",
+		"variable":       "name = var.name",
+		"mixed labels":   "a \"x\" {}\na {}",
+		"attr and block": "a = 1\na {}",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := Hcl{}
+			result, err := parser.Parse([]byte(data))
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if result != nil {
+				t.Errorf("Expected nil result, got %v", result)
+			}
+		})
+	}
+}
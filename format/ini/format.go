@@ -0,0 +1,218 @@
+package ini
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Ini formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("ini", Ini{})
+	format.RegisterFormatter("cfg", Ini{})
+}
+
+// Ini implements the Formatter interface for INI format.
+//
+// INI documents are converted with the following rules:
+//   - Keys before the first section belong to the root object.
+//   - A section becomes a nested object, dotted section names nest deeper,
+//     e.g. keys of [server.tls] end up in {"server": {"tls": {...}}}. Repeated sections are merged.
+//   - Keys are separated from values by the first '=' or ':'. A key repeated within a section,
+//     or a key ending with "[]", becomes a list of values in source order.
+//   - Lines starting with ';' or '#' are comments. In unquoted values, a ';' or '#' preceded by
+//     whitespace starts an inline comment.
+//   - Unquoted values are trimmed. Every value stays a string; format.StructToMessage converts it
+//     according to the type of the field, e.g. true and false (case-insensitive) to booleans
+//     for bool fields, while protojson converts numbers where needed.
+//   - Double-quoted values are strings supporting the escapes \", \\, \n, \r and \t.
+//     Single-quoted values are literal strings.
+type Ini struct{}
+
+// Parse converts INI-formatted byte data into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The INI-formatted byte slice to be parsed
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if parsing fails (e.g., malformed lines or conflicting keys)
+func (Ini) Parse(data []byte) (*structpb.Struct, error) {
+	root := make(map[string]any)
+	section := root
+	// lists tracks the keys of every section that hold a list of values
+	lists := make(map[string]map[string]bool)
+	sectionName := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		// Section header
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("ini: line %d: unterminated section header", lineNum)
+			}
+			if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return nil, fmt.Errorf("ini: line %d: unexpected text after section header", lineNum)
+			}
+			sectionName = strings.TrimSpace(line[1:end])
+			var err error
+			section, err = lookupSection(root, sectionName)
+			if err != nil {
+				return nil, fmt.Errorf("ini: line %d: %w", lineNum, err)
+			}
+			continue
+		}
+
+		// Key/value pair
+		sep := strings.IndexAny(line, "=:")
+		if sep <= 0 {
+			return nil, fmt.Errorf("ini: line %d: expected key=value", lineNum)
+		}
+		key := strings.TrimSpace(line[:sep])
+		value, err := parseValue(strings.TrimSpace(line[sep+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("ini: line %d: %w", lineNum, err)
+		}
+		forceList := strings.HasSuffix(key, "[]")
+		if forceList {
+			key = strings.TrimSpace(strings.TrimSuffix(key, "[]"))
+		}
+		if key == "" {
+			return nil, fmt.Errorf("ini: line %d: empty key", lineNum)
+		}
+
+		sectionLists := lists[sectionName]
+		if sectionLists == nil {
+			sectionLists = make(map[string]bool)
+			lists[sectionName] = sectionLists
+		}
+		existing, ok := section[key]
+		switch {
+		case ok && sectionLists[key]:
+			section[key] = append(existing.([]any), value)
+		case ok:
+			if _, isSection := existing.(map[string]any); isSection {
+				return nil, fmt.Errorf("ini: line %d: key %q conflicts with section of the same name", lineNum, key)
+			}
+			section[key] = []any{existing, value}
+			sectionLists[key] = true
+		case forceList:
+			section[key] = []any{value}
+			sectionLists[key] = true
+		default:
+			section[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(root)
+}
+
+// lookupSection returns the object of a dotted section name, creating it if needed
+func lookupSection(root map[string]any, name string) (map[string]any, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty section name")
+	}
+	section := root
+	for _, part := range strings.Split(name, ".") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid section name %q", name)
+		}
+		child, ok := section[part]
+		if !ok {
+			child = make(map[string]any)
+			section[part] = child
+		}
+		childSection, ok := child.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("section %q conflicts with key %q", name, part)
+		}
+		section = childSection
+	}
+	return section, nil
+}
+
+// parseValue converts a raw value into a string according to the quoting rules
+func parseValue(raw string) (any, error) {
+	if raw == "" {
+		return "", nil
+	}
+	switch raw[0] {
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			switch c {
+			case '"':
+				if err := checkTrailing(raw[i+1:]); err != nil {
+					return nil, err
+				}
+				return sb.String(), nil
+			case '\\':
+				i++
+				if i >= len(raw) {
+					return nil, fmt.Errorf("unterminated escape sequence")
+				}
+				switch raw[i] {
+				case '"', '\\':
+					sb.WriteByte(raw[i])
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					return nil, fmt.Errorf("invalid escape sequence \\%c", raw[i])
+				}
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return nil, fmt.Errorf("unterminated double-quoted value")
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated single-quoted value")
+		}
+		if err := checkTrailing(raw[end+2:]); err != nil {
+			return nil, err
+		}
+		return raw[1 : end+1], nil
+	}
+
+	// Strip inline comments from unquoted values
+	for i := 1; i < len(raw); i++ {
+		if (raw[i] == ';' || raw[i] == '#') && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			raw = strings.TrimSpace(raw[:i])
+			break
+		}
+	}
+	return raw, nil
+}
+
+// checkTrailing ensures only whitespace or a comment follows a quoted value
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != ';' && rest[0] != '#' {
+		return fmt.Errorf("unexpected text after quoted value")
+	}
+	return nil
+}
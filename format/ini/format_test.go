@@ -0,0 +1,129 @@
+package ini
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestParse_Success tests successful parsing of sections, nested sections and lists.
+func TestParse_Success(t *testing.T) {
+	data := []byte(`
+; global settings
+name = Alice
+debug = true
+
+[server]
+addr = 0.0.0.0
+port: 8080 ; inline comment
+
+[server.tls]
+cert = "/etc/tls/cert.pem"
+key = '/etc/tls/#key.pem'
+
+[upstream]
+host = a.example.com
+host = b.example.com
+tag[] = only
+
+# sections may be reopened
+[server]
+timeout = 5s
+`)
+	parser := Ini{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"name":  "Alice",
+		"debug": "true",
+		"server": map[string]interface{}{
+			"addr":    "0.0.0.0",
+			"port":    "8080",
+			"timeout": "5s",
+			"tls": map[string]interface{}{
+				"cert": "/etc/tls/cert.pem",
+				"key":  "/etc/tls/#key.pem",
+			},
+		},
+		"upstream": map[string]interface{}{
+			"host": []interface{}{"a.example.com", "b.example.com"},
+			"tag":  []interface{}{"only"},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_Bool tests that booleans are converted according to the field type.
+func TestParse_Bool(t *testing.T) {
+	data := []byte("javaPackage = true\njava_multiple_files = TRUE\ndeprecated = false\n")
+	result, err := Ini{}.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	options := &descriptorpb.FileOptions{}
+	if err := format.StructToMessage(result, options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options.GetJavaPackage() != "true" || !options.GetJavaMultipleFiles() || options.Deprecated == nil || options.GetDeprecated() {
+		t.Errorf("Unexpected message %v", options)
+	}
+}
+
+// TestParse_QuotedValues tests the quoting policy.
+func TestParse_QuotedValues(t *testing.T) {
+	data := []byte(`
+a = "true"
+b = "line\nbreak \"quoted\""
+c = 'C:\path'
+d = value # comment
+e = value#not-a-comment
+`)
+	parser := Ini{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"a": "true",
+		"b": "line\nbreak \"quoted\"",
+		"c": `C:\path`,
+		"d": "value",
+		"e": "value#not-a-comment",
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_InvalidINI tests error handling for invalid INI format.
+func TestParse_InvalidINI(t *testing.T) {
+	tests := map[string]string{
+		"unterminated section": "[server",
+		"missing separator":    "[server]\nport",
+		"unterminated quote":   `a = "value`,
+		"section conflict":     "server = 1\n[server]",
+		"key conflict":         "[server]\n[root]\nserver = 1\n[root.server]\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := Ini{}
+			result, err := parser.Parse([]byte(data))
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if result != nil {
+				t.Errorf("Expected nil result, got %v", result)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package json
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
 	"github.com/soyacen/gonfig/format"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -30,7 +34,89 @@ func (Json) Parse(data []byte) (*structpb.Struct, error) {
 		return nil, err
 	}
 	if err := value.UnmarshalJSON(data); err != nil {
-		return nil, err
+		position, _ := format.ProtojsonPosition(err)
+		return nil, &format.ParseError{Position: position, Err: err}
 	}
 	return value, nil
 }
+
+// Locate returns the position of the key of the field at path, or of the list item, in the JSON data.
+//
+// Args:
+//
+//	data ([]byte): JSON content
+//	path (string): Field path, e.g. "redis.port" or "routes[0].path"
+//
+// Returns:
+//
+//	format.Position: Position of the field
+//	bool: Whether the field was found
+func (Json) Locate(data []byte, path string) (format.Position, bool) {
+	segments := format.SplitPath(path)
+	if segments == nil {
+		return format.Position{}, false
+	}
+	offset, ok := locate(json.NewDecoder(bytes.NewReader(data)), data, segments)
+	if !ok {
+		return format.Position{}, false
+	}
+	return format.PositionOf(data, offset), true
+}
+
+// locate returns the offset of the key of the field, or of the item, at the path segments in the next value
+func locate(decoder *json.Decoder, data []byte, segments []any) (int, bool) {
+	start := tokenStart(decoder, data)
+	if len(segments) == 0 {
+		return start, true
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return 0, false
+	}
+	switch token {
+	case json.Delim('{'):
+		key, ok := segments[0].(string)
+		for ok && decoder.More() {
+			keyStart := tokenStart(decoder, data)
+			name, err := decoder.Token()
+			if err != nil {
+				return 0, false
+			}
+			if name == key {
+				if len(segments) == 1 {
+					return keyStart, true
+				}
+				return locate(decoder, data, segments[1:])
+			}
+			if !skip(decoder) {
+				return 0, false
+			}
+		}
+	case json.Delim('['):
+		index, ok := segments[0].(int)
+		for i := 0; ok && decoder.More(); i++ {
+			if i == index {
+				return locate(decoder, data, segments[1:])
+			}
+			if !skip(decoder) {
+				return 0, false
+			}
+		}
+	}
+	return 0, false
+}
+
+// tokenStart returns the offset of the next token, skipping whitespace and separators
+func tokenStart(decoder *json.Decoder, data []byte) int {
+	offset := int(decoder.InputOffset())
+	for offset < len(data) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// skip skips the next value
+func skip(decoder *json.Decoder) bool {
+	var value json.RawMessage
+	return decoder.Decode(&value) == nil
+}
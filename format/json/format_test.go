@@ -1,8 +1,11 @@
 package json
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+
+	"github.com/soyacen/gonfig/format"
 )
 
 // TestParse_Success tests successful parsing of valid JSON data.
@@ -43,3 +46,46 @@ func TestParse_InvalidJson(t *testing.T) {
 		t.Errorf("Expected nil result, got %v", result)
 	}
 }
+
+// TestParse_ErrorPosition tests that syntax errors report their line and column.
+func TestParse_ErrorPosition(t *testing.T) {
+	data := []byte("{\n  \"name\": \"Alice\",\n  \"age\": }")
+	_, err := Json{}.Parse(data)
+	var parseErr *format.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *format.ParseError, got %v", err)
+	}
+	if parseErr.Position != (format.Position{Line: 3, Column: 10}) {
+		t.Errorf("Expected position 3:10, got %v", parseErr.Position)
+	}
+}
+
+// TestLocate tests finding the positions of fields and list items.
+func TestLocate(t *testing.T) {
+	data := []byte(`{
+  "name": "Alice",
+  "redis": {"addr": "localhost", "port": "abc"},
+  "routes": [
+    {"path": "/api"},
+    {"path": "/日本", "upstream": "api"}
+  ]
+}`)
+	tests := []struct {
+		path     string
+		expected format.Position
+		found    bool
+	}{
+		{"name", format.Position{Line: 2, Column: 3}, true},
+		{"redis.port", format.Position{Line: 3, Column: 34}, true},
+		{"routes[1]", format.Position{Line: 6, Column: 5}, true},
+		{"routes[1].upstream", format.Position{Line: 6, Column: 21}, true},
+		{"routes[2]", format.Position{}, false},
+		{"redis.missing", format.Position{}, false},
+	}
+	for _, tt := range tests {
+		position, found := Json{}.Locate(data, tt.path)
+		if found != tt.found || position != tt.expected {
+			t.Errorf("Locate(%q) = %v, %v; want %v, %v", tt.path, position, found, tt.expected, tt.found)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package json5
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Json5 formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("json5", Json5{})
+	format.RegisterFormatter("jsonc", Json5{})
+}
+
+// Json5 implements the Formatter interface for JSON5 format.
+// Since JSON5 is a superset of JSONC (JSON with comments), it is also registered for "jsonc".
+//
+// In addition to JSON, the following syntax is accepted:
+//   - single-line (//) and multi-line (/* */) comments
+//   - trailing commas in objects and arrays
+//   - unquoted object keys that are ECMAScript identifiers
+//   - single-quoted strings, \x and \v escapes and line continuations in strings
+//   - hexadecimal numbers, leading or trailing decimal points and an explicit plus sign
+//   - Infinity and NaN, which become the strings "Infinity", "-Infinity" and "NaN" accepted by
+//     protojson for float and double fields
+type Json5 struct{}
+
+// Parse converts JSON5-formatted byte data into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The JSON5-formatted byte slice to be parsed, its top-level value must be an object
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error with line and column if parsing fails
+func (Json5) Parse(data []byte) (*structpb.Struct, error) {
+	p := &parser{data: string(data), line: 1, col: 1}
+	p.skipBOM()
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.peek() != '{' {
+		return nil, p.errorf("top-level value must be an object")
+	}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected character %q after top-level value", p.peek())
+	}
+	return structpb.NewStruct(value.(map[string]any))
+}
+
+// parser is a recursive descent parser for JSON5
+type parser struct {
+	data string
+	pos  int
+	line int
+	col  int
+}
+
+// errorf returns an error annotated with the current position
+func (p *parser) errorf(msg string, args ...any) error {
+	return fmt.Errorf("json5: line %d, column %d: %s", p.line, p.col, fmt.Sprintf(msg, args...))
+}
+
+// eof reports whether the whole input has been consumed
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+// peek returns the current rune without consuming it
+func (p *parser) peek() rune {
+	if p.eof() {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRuneInString(p.data[p.pos:])
+	return r
+}
+
+// next consumes and returns the current rune
+func (p *parser) next() rune {
+	r, size := utf8.DecodeRuneInString(p.data[p.pos:])
+	p.pos += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+	return r
+}
+
+// consume consumes s if the input continues with it
+func (p *parser) consume(s string) bool {
+	if !strings.HasPrefix(p.data[p.pos:], s) {
+		return false
+	}
+	for range s {
+		p.next()
+	}
+	return true
+}
+
+// skipBOM skips a leading byte order mark
+func (p *parser) skipBOM() {
+	if strings.HasPrefix(p.data, "\uFEFF") {
+		p.pos += len("\uFEFF")
+	}
+}
+
+// skip skips whitespace and comments
+func (p *parser) skip() error {
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case isSpace(r):
+			p.next()
+		case strings.HasPrefix(p.data[p.pos:], "//"):
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		case strings.HasPrefix(p.data[p.pos:], "/*"):
+			p.consume("/*")
+			for !p.consume("*/") {
+				if p.eof() {
+					return p.errorf("unterminated comment")
+				}
+				p.next()
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// isSpace reports whether r is JSON5 whitespace
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\v', '\f', '\u00A0', '\uFEFF', '\u2028', '\u2029':
+		return true
+	}
+	return unicode.Is(unicode.Zs, r)
+}
+
+// value parses any JSON5 value
+func (p *parser) value() (any, error) {
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch r := p.peek(); {
+	case r == '{':
+		return p.object()
+	case r == '[':
+		return p.array()
+	case r == '"' || r == '\'':
+		return p.string()
+	case r == '-' || r == '+' || r == '.' || (r >= '0' && r <= '9'):
+		return p.number()
+	case r == 'I' || r == 'N':
+		return p.number()
+	case p.consume("true"):
+		return true, nil
+	case p.consume("false"):
+		return false, nil
+	case p.consume("null"):
+		return nil, nil
+	default:
+		return nil, p.errorf("unexpected character %q", r)
+	}
+}
+
+// object parses an object, allowing unquoted keys and a trailing comma
+func (p *parser) object() (any, error) {
+	p.next() // '{'
+	obj := make(map[string]any)
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.peek() == '}' {
+			p.next()
+			return obj, nil
+		}
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.next() != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated object")
+		}
+		switch p.next() {
+		case ',':
+		case '}':
+			return obj, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+// key parses a quoted key or an identifier
+func (p *parser) key() (string, error) {
+	if r := p.peek(); r == '"' || r == '\'' {
+		return p.string()
+	}
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if r == '$' || r == '_' || unicode.IsLetter(r) || (p.pos > start && (unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || unicode.Is(unicode.Pc, r))) {
+			p.next()
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		return "", p.errorf("invalid object key starting with %q", p.peek())
+	}
+	return p.data[start:p.pos], nil
+}
+
+// array parses an array, allowing a trailing comma
+func (p *parser) array() (any, error) {
+	p.next() // '['
+	list := make([]any, 0)
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.next()
+			return list, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.next() {
+		case ',':
+		case ']':
+			return list, nil
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// string parses a single- or double-quoted string
+func (p *parser) string() (string, error) {
+	quote := p.next()
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		r := p.next()
+		switch {
+		case r == quote:
+			return sb.String(), nil
+		case r == '\n' || r == '\r':
+			return "", p.errorf("unescaped line break in string")
+		case r != '\\':
+			sb.WriteRune(r)
+			continue
+		}
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		switch e := p.next(); e {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case '0':
+			sb.WriteByte(0)
+		case 'x':
+			code, err := p.hex(2)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(rune(code))
+		case 'u':
+			code, err := p.hex(4)
+			if err != nil {
+				return "", err
+			}
+			// Combine UTF-16 surrogate pairs
+			if code >= 0xD800 && code < 0xDC00 && strings.HasPrefix(p.data[p.pos:], "\\u") {
+				save, line, col := p.pos, p.line, p.col
+				p.consume("\\u")
+				low, err := p.hex(4)
+				if err == nil && low >= 0xDC00 && low < 0xE000 {
+					code = (code-0xD800)<<10 + (low - 0xDC00) + 0x10000
+				} else {
+					p.pos, p.line, p.col = save, line, col
+				}
+			}
+			sb.WriteRune(rune(code))
+		case '\n', '\u2028', '\u2029':
+			// Line continuation
+		case '\r':
+			// Line continuation with CRLF
+			if p.peek() == '\n' {
+				p.next()
+			}
+		default:
+			if e >= '1' && e <= '9' {
+				return "", p.errorf("invalid escape sequence \\%c", e)
+			}
+			sb.WriteRune(e)
+		}
+	}
+}
+
+// hex parses n hexadecimal digits
+func (p *parser) hex(n int) (int, error) {
+	if p.pos+n > len(p.data) {
+		return 0, p.errorf("invalid hexadecimal escape")
+	}
+	code, err := strconv.ParseUint(p.data[p.pos:p.pos+n], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid hexadecimal escape")
+	}
+	for i := 0; i < n; i++ {
+		p.next()
+	}
+	return int(code), nil
+}
+
+// number parses a decimal or hexadecimal number, Infinity or NaN
+func (p *parser) number() (any, error) {
+	sign := 1.0
+	signText := ""
+	switch p.peek() {
+	case '-':
+		p.next()
+		sign, signText = -1, "-"
+	case '+':
+		p.next()
+	}
+	switch {
+	case p.consume("Infinity"):
+		return signText + "Infinity", nil
+	case p.consume("NaN"):
+		return "NaN", nil
+	case strings.HasPrefix(p.data[p.pos:], "0x") || strings.HasPrefix(p.data[p.pos:], "0X"):
+		p.consume(p.data[p.pos : p.pos+2])
+		start := p.pos
+		for !p.eof() && strings.ContainsRune("0123456789abcdefABCDEF", p.peek()) {
+			p.next()
+		}
+		n, err := strconv.ParseUint(p.data[start:p.pos], 16, 64)
+		if err != nil {
+			return nil, p.errorf("invalid hexadecimal number")
+		}
+		return sign * float64(n), nil
+	}
+	start := p.pos
+	for !p.eof() && strings.ContainsRune("0123456789.eE+-", p.peek()) {
+		// A sign is only part of the number right after the exponent marker
+		if r := p.peek(); (r == '+' || r == '-') && p.pos > start && !strings.ContainsRune("eE", rune(p.data[p.pos-1])) {
+			break
+		}
+		p.next()
+	}
+	text := p.data[start:p.pos]
+	if text == "" || text == "." {
+		return nil, p.errorf("invalid number")
+	}
+	if len(text) > 1 && text[0] == '0' && text[1] >= '0' && text[1] <= '9' {
+		return nil, p.errorf("invalid number %q with leading zero", text)
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(f, 0) {
+		return nil, p.errorf("invalid number %q", text)
+	}
+	return sign * f, nil
+}
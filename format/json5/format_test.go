@@ -0,0 +1,110 @@
+package json5
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParse_Success tests successful parsing of JSON5 syntax.
+func TestParse_Success(t *testing.T) {
+	data := []byte(`// hand-edited configuration
+{
+  /* server settings */
+  server: {
+    addr: '0.0.0.0',
+    port: 8080, // trailing comment
+    $tls: true,
+  },
+  "hosts": ['a', "b",],
+  hex: 0xFF,
+  half: .5,
+  whole: 2.,
+  positive: +1,
+  exp: 1e3,
+  quote: 'it\'s "quoted"',
+  multi: 'line \
+continued',
+  escapes: "\x41é😀\t",
+  inf: -Infinity,
+  nan: NaN,
+  nothing: null,
+}`)
+	parser := Json5{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"server": map[string]interface{}{
+			"addr": "0.0.0.0",
+			"port": float64(8080),
+			"$tls": true,
+		},
+		"hosts":    []interface{}{"a", "b"},
+		"hex":      float64(255),
+		"half":     0.5,
+		"whole":    float64(2),
+		"positive": float64(1),
+		"exp":      float64(1000),
+		"quote":    `it's "quoted"`,
+		"multi":    "line continued",
+		"escapes":  "Aé😀\t",
+		"inf":      "-Infinity",
+		"nan":      "NaN",
+		"nothing":  nil,
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_JSONC tests that plain JSON with comments and trailing commas is accepted.
+func TestParse_JSONC(t *testing.T) {
+	data := []byte(`{
+  // comment
+  "name": "Alice",
+  "age": 30,
+}`)
+	parser := Json5{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"name": "Alice",
+		"age":  float64(30),
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_InvalidJSON5 tests error handling for invalid JSON5.
+func TestParse_InvalidJSON5(t *testing.T) {
+	tests := map[string]string{
+		"not an object":        `[1, 2]`,
+		"unterminated object":  `{a: 1`,
+		"unterminated string":  `{a: 'x}`,
+		"unterminated comment": `{a: 1 /* }`,
+		"double comma":         `{a: 1,,}`,
+		"leading zero":         `{a: 01}`,
+		"invalid key":          `{1a: 1}`,
+		"trailing data":        `{} x`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := Json5{}
+			result, err := parser.Parse([]byte(data))
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if result != nil {
+				t.Errorf("Expected nil result, got %v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package jsonnet provides a formatter evaluating Jsonnet configuration files
+package jsonnet
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var (
+	_ format.SourceFormatter    = Jsonnet{}
+	_ format.DependentFormatter = Jsonnet{}
+)
+
+// init registers the Jsonnet formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("jsonnet", Jsonnet{})
+	format.RegisterFormatter("libsonnet", Jsonnet{})
+}
+
+// Jsonnet implements the Formatter interface for Jsonnet format.
+// The program must evaluate to an object.
+//
+// Relative imports resolve against the directory of the source the formatter is bound to by
+// WithSource, which the file resource does automatically, and then against JPaths.
+// Imported files are reported by Dependencies, so the file resource reloads when they change.
+//
+// To pass variables, re-register the formatter, for example:
+//
+//	format.RegisterFormatter("jsonnet", jsonnet.Jsonnet{ExtVarsEnvPrefix: "JSONNET_", TLAVars: map[string]string{"env": "prod"}})
+type Jsonnet struct {
+	// ExtVars are external variables accessible with std.extVar, bound to strings
+	ExtVars map[string]string
+	// ExtCode are external variables accessible with std.extVar, bound to Jsonnet code
+	ExtCode map[string]string
+	// ExtVarsEnvPrefix, if not empty, passes every environment variable with the prefix as an external
+	// variable named without the prefix, e.g. JSONNET_REGION becomes std.extVar("REGION")
+	ExtVarsEnvPrefix string
+	// TLAVars are top-level arguments of a program evaluating to a function, bound to strings
+	TLAVars map[string]string
+	// TLACode are top-level arguments of a program evaluating to a function, bound to Jsonnet code
+	TLACode map[string]string
+	// JPaths are the library search paths used for imports not found relative to the importing file
+	JPaths []string
+
+	// filename is the path of the source, set by WithSource
+	filename string
+	// dependencies records the imported files, set by WithSource
+	dependencies *dependencies
+}
+
+// WithSource returns a formatter resolving relative imports against the directory of the named file
+// and recording the imported files.
+//
+// Args:
+//
+//	name (string): Path of the Jsonnet file
+//
+// Returns:
+//
+//	format.Formatter: Formatter bound to the file
+func (j Jsonnet) WithSource(name string) format.Formatter {
+	j.filename = name
+	j.dependencies = &dependencies{}
+	return j
+}
+
+// Dependencies returns the paths of the files imported by the latest successful Parse call,
+// and of the files imported by failed calls since, as fixing them may resolve the error.
+//
+// Returns:
+//
+//	[]string: Paths of the imported files, nil if the formatter is not bound to a source
+func (j Jsonnet) Dependencies() []string {
+	if j.dependencies == nil {
+		return nil
+	}
+	return j.dependencies.list()
+}
+
+// Parse evaluates Jsonnet-formatted byte data into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The Jsonnet program to be evaluated
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the evaluated data
+//	error: An error with the location if evaluation fails
+func (j Jsonnet) Parse(data []byte) (*structpb.Struct, error) {
+	vm := jsonnet.MakeVM()
+	if j.ExtVarsEnvPrefix != "" {
+		for _, env := range os.Environ() {
+			key, value, _ := strings.Cut(env, "=")
+			if name, ok := strings.CutPrefix(key, j.ExtVarsEnvPrefix); ok && name != "" {
+				vm.ExtVar(name, value)
+			}
+		}
+	}
+	for key, value := range j.ExtVars {
+		vm.ExtVar(key, value)
+	}
+	for key, value := range j.ExtCode {
+		vm.ExtCode(key, value)
+	}
+	for key, value := range j.TLAVars {
+		vm.TLAVar(key, value)
+	}
+	for key, value := range j.TLACode {
+		vm.TLACode(key, value)
+	}
+
+	// A new FileImporter per evaluation, as it caches the content of imported files;
+	// the imports of every evaluation are recorded anew, so that removed imports are no longer reported
+	imported := &dependencies{}
+	vm.Importer(&importer{
+		Importer:     &jsonnet.FileImporter{JPaths: j.JPaths},
+		dependencies: imported,
+	})
+
+	filename := j.filename
+	if filename == "" {
+		filename = "config.jsonnet"
+	}
+	// EvaluateSnippet resolves relative imports against the directory of the file, unlike EvaluateAnonymousSnippet;
+	// EvaluateFile cannot be used as the data may differ from the file, e.g. once decompressed or rendered
+	output, err := vm.EvaluateSnippet(filename, string(data))
+	if err != nil {
+		if j.dependencies != nil {
+			j.dependencies.merge(imported.list())
+		}
+		return nil, fmt.Errorf("jsonnet: %w", err)
+	}
+	if j.dependencies != nil {
+		j.dependencies.replace(imported.list())
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON([]byte(output)); err != nil {
+		return nil, fmt.Errorf("jsonnet: top-level value must be an object: %w", err)
+	}
+	return s, nil
+}
+
+// importer records the files found by an importer
+type importer struct {
+	jsonnet.Importer
+	dependencies *dependencies
+}
+
+// Import implements jsonnet.Importer
+func (i *importer) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	contents, foundAt, err := i.Importer.Import(importedFrom, importedPath)
+	if err == nil && i.dependencies != nil {
+		i.dependencies.add(foundAt)
+	}
+	return contents, foundAt, err
+}
+
+// dependencies is a set of file paths safe for concurrent use
+type dependencies struct {
+	mutex sync.Mutex
+	paths []string
+}
+
+// add adds a path to the set
+func (d *dependencies) add(path string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !slices.Contains(d.paths, path) {
+		d.paths = append(d.paths, path)
+	}
+}
+
+// merge adds paths to the set
+func (d *dependencies) merge(paths []string) {
+	for _, path := range paths {
+		d.add(path)
+	}
+}
+
+// replace replaces the paths of the set
+func (d *dependencies) replace(paths []string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.paths = paths
+}
+
+// list returns a copy of the paths in the set
+func (d *dependencies) list() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return slices.Clone(d.paths)
+}
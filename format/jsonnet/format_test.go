@@ -0,0 +1,148 @@
+package jsonnet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gonfig/resource/file"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestParse_Success tests successful evaluation of Jsonnet data with external variables and top-level arguments.
+func TestParse_Success(t *testing.T) {
+	t.Setenv("JSONNET_REGION", "eu")
+	formatter := Jsonnet{
+		ExtVarsEnvPrefix: "JSONNET_",
+		ExtVars:          map[string]string{"env": "prod"},
+		ExtCode:          map[string]string{"replicas": "1 + 2"},
+		TLAVars:          map[string]string{"name": "app"},
+	}
+	data := []byte(`
+function(name) {
+  local server = { addr: '0.0.0.0', port: 8080 },
+  name: name,
+  env: std.extVar('env'),
+  region: std.extVar('REGION'),
+  replicas: std.extVar('replicas'),
+  server: server { port+: 1 },
+  hosts: [h + '.example.com' for h in ['a', 'b']],
+}
+`)
+	s, err := formatter.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"name":     "app",
+		"env":      "prod",
+		"region":   "eu",
+		"replicas": float64(3),
+		"server":   map[string]any{"addr": "0.0.0.0", "port": float64(8081)},
+		"hosts":    []any{"a.example.com", "b.example.com"},
+	}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+}
+
+// TestParse_Imports tests that relative imports resolve against the source and are reported as dependencies.
+func TestParse_Imports(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(dir, "lib", "defaults.libsonnet")
+	if err := os.WriteFile(lib, []byte(`{ port: 8080 }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	formatter := Jsonnet{}.WithSource(filepath.Join(dir, "config.jsonnet"))
+	s, err := formatter.Parse([]byte(`(import 'lib/defaults.libsonnet') { addr: 'localhost' }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"addr": "localhost", "port": float64(8080)}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+	dependencies := formatter.(Jsonnet).Dependencies()
+	if len(dependencies) != 1 || filepath.Clean(dependencies[0]) != lib {
+		t.Errorf("expected dependencies [%s]; got %v", lib, dependencies)
+	}
+
+	// Removed imports are no longer reported
+	if _, err := formatter.Parse([]byte(`{ addr: 'localhost' }`)); err != nil {
+		t.Fatal(err)
+	}
+	if dependencies := formatter.(Jsonnet).Dependencies(); len(dependencies) != 0 {
+		t.Errorf("expected no dependencies; got %v", dependencies)
+	}
+}
+
+// TestParse_InvalidJsonnet tests error handling for Jsonnet data that fails to evaluate.
+func TestParse_InvalidJsonnet(t *testing.T) {
+	cases := map[string]string{
+		"syntax":    "{ a: ",
+		"runtime":   "{ a: error 'boom' }",
+		"import":    "import 'missing.libsonnet'",
+		"top-level": "[1, 2]",
+	}
+	for name, data := range cases {
+		if _, err := (Jsonnet{}).Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestWatch_Imports tests that the file resource reloads a Jsonnet file when a relatively imported file changes.
+func TestWatch_Imports(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(dir, "lib", "defaults.libsonnet")
+	if err := os.WriteFile(lib, []byte(`{ port: 8080 }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "config.jsonnet")
+	if err := os.WriteFile(filename, []byte(`(import 'lib/defaults.libsonnet') { addr: 'localhost' }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := file.New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c := make(chan *structpb.Struct, 16)
+	stop, err := resource.Watch(ctx, func(value *structpb.Struct) {
+		c <- value
+	}, func(err error) {
+		t.Logf("Error: %v", err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	if err := os.WriteFile(lib, []byte(`{ port: 9090 }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case value := <-c:
+			if value.GetFields()["port"].GetNumberValue() == 9090 {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the change of the import")
+		}
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/soyacen/gonfig/format/jsonnet
+
+go 1.25.0
+
+replace github.com/soyacen/gonfig => ../../
+
+require (
+	github.com/google/go-jsonnet v0.21.0
+	github.com/soyacen/gonfig v0.0.8
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.9.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+	sigs.k8s.io/yaml v1.4.0 // indirect
+)
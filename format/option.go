@@ -0,0 +1,192 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Option configures a formatter when it is registered.
+// It returns an error if it does not apply to the formatter, e.g. WithStrict for a formatter without strict mode.
+type Option func(Formatter) (Formatter, error)
+
+// StrictFormatter is implemented by formatters supporting a strict mode,
+// in which input that is otherwise accepted leniently, such as duplicate keys, is rejected.
+type StrictFormatter interface {
+	Formatter
+	// Strict returns the formatter in strict mode
+	//
+	// Returns:
+	//   Formatter: Strict formatter
+	Strict() Formatter
+}
+
+// WithStrict registers the strict mode of a StrictFormatter.
+// Formatters without a strict mode are rejected by Register, so that the setting is never silently ignored.
+//
+// Returns:
+//
+//	Option: Option for Register
+func WithStrict() Option {
+	return func(formatter Formatter) (Formatter, error) {
+		if strictFormatter, ok := formatter.(StrictFormatter); ok {
+			return strictFormatter.Strict(), nil
+		}
+		return nil, fmt.Errorf("format: %T does not support strict mode", formatter)
+	}
+}
+
+// WithKeyCase converts every object key of the parsed data, e.g. with SnakeCase to match proto field names
+// in files using kebab-case keys. It does not apply to data decoded directly into messages by a MessageFormatter.
+//
+// Args:
+//
+//	keyCase (func(string) string): Function converting a key
+//
+// Returns:
+//
+//	Option: Option for Register
+func WithKeyCase(keyCase func(string) string) Option {
+	return func(formatter Formatter) (Formatter, error) {
+		return newKeyCaseFormatter(formatter, keyCase), nil
+	}
+}
+
+// SnakeCase converts a key to snake_case, e.g. "maxConns", "MaxConns" and "max-conns" become "max_conns"
+//
+// Args:
+//
+//	key (string): Key to convert
+//
+// Returns:
+//
+//	string: Converted key
+func SnakeCase(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			// A word starts at an upper-case letter following a lower-case letter or digit,
+			// or at the last upper-case letter of an acronym, e.g. "HTTPServer" becomes "http_server"
+			if i > 0 && runes[i-1] != '_' && runes[i-1] != '-' && runes[i-1] != ' ' &&
+				(!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// LowerCamelCase converts a key to lowerCamelCase, the JSON names of proto fields,
+// e.g. "max_conns" and "max-conns" become "maxConns"
+//
+// Args:
+//
+//	key (string): Key to convert
+//
+// Returns:
+//
+//	string: Converted key
+func LowerCamelCase(key string) string {
+	var b strings.Builder
+	upper := false
+	for i, r := range key {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			upper = b.Len() > 0
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		case i == 0:
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// newKeyCaseFormatter creates a keyCaseFormatter, keeping the Unmarshal method of a MessageFormatter
+func newKeyCaseFormatter(formatter Formatter, keyCase func(string) string) Formatter {
+	f := &keyCaseFormatter{formatter: formatter, keyCase: keyCase}
+	if _, ok := formatter.(MessageFormatter); ok {
+		return &keyCaseMessageFormatter{keyCaseFormatter: f}
+	}
+	return f
+}
+
+// keyCaseFormatter converts the object keys of the data parsed by a formatter
+type keyCaseFormatter struct {
+	formatter Formatter
+	keyCase   func(string) string
+}
+
+// Parse parses data with the formatter and converts the object keys
+func (f *keyCaseFormatter) Parse(data []byte) (*structpb.Struct, error) {
+	value, err := f.formatter.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return convertKeys(value, f.keyCase), nil
+}
+
+// WithSource binds the formatter to a source if it is a SourceFormatter
+func (f *keyCaseFormatter) WithSource(name string) Formatter {
+	sourceFormatter, ok := f.formatter.(SourceFormatter)
+	if !ok {
+		return f
+	}
+	return newKeyCaseFormatter(sourceFormatter.WithSource(name), f.keyCase)
+}
+
+// Dependencies returns the dependencies of the formatter if it is a DependentFormatter
+func (f *keyCaseFormatter) Dependencies() []string {
+	if dependentFormatter, ok := f.formatter.(DependentFormatter); ok {
+		return dependentFormatter.Dependencies()
+	}
+	return nil
+}
+
+// keyCaseMessageFormatter is a keyCaseFormatter keeping the Unmarshal method of a MessageFormatter
+type keyCaseMessageFormatter struct {
+	*keyCaseFormatter
+}
+
+// Unmarshal decodes data into a message with the formatter
+func (f *keyCaseMessageFormatter) Unmarshal(data []byte, message proto.Message) error {
+	return f.formatter.(MessageFormatter).Unmarshal(data, message)
+}
+
+// convertKeys converts the keys of a struct and its nested structs
+func convertKeys(value *structpb.Struct, keyCase func(string) string) *structpb.Struct {
+	fields := make(map[string]*structpb.Value, len(value.GetFields()))
+	for key, field := range value.GetFields() {
+		fields[keyCase(key)] = convertValueKeys(field, keyCase)
+	}
+	return &structpb.Struct{Fields: fields}
+}
+
+// convertValueKeys converts the keys of the structs in a value
+func convertValueKeys(value *structpb.Value, keyCase func(string) string) *structpb.Value {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StructValue:
+		return structpb.NewStructValue(convertKeys(kind.StructValue, keyCase))
+	case *structpb.Value_ListValue:
+		values := make([]*structpb.Value, len(kind.ListValue.GetValues()))
+		for i, item := range kind.ListValue.GetValues() {
+			values[i] = convertValueKeys(item, keyCase)
+		}
+		return structpb.NewListValue(&structpb.ListValue{Values: values})
+	default:
+		return value
+	}
+}
@@ -0,0 +1,346 @@
+package properties
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Properties formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("properties", Properties{})
+}
+
+// Properties implements the Formatter interface for Java .properties format.
+//
+// Lines are read as in java.util.Properties: '#' and '!' start comment lines, a key ends at the
+// first unescaped '=', ':' or whitespace, a trailing backslash continues the line, and the escapes
+// \t, \n, \r, \f and \uXXXX are supported (any other escaped character stands for itself).
+// The data is expected to be UTF-8 encoded.
+//
+// Keys are then converted into nested objects:
+//   - dotted keys nest, e.g. spring.redis.host=localhost becomes {"spring": {"redis": {"host": "localhost"}}}
+//   - indexed keys build lists, e.g. servers[0].host=a becomes {"servers": [{"host": "a"}]};
+//     indices of a list must be contiguous and start at 0
+//   - values stay strings, format.StructToMessage converts them for numeric and bool fields,
+//     e.g. ssl=true sets a bool field and a string field alike
+//   - a key that is both a value and a parent of other keys is an error
+//
+// As in java.util.Properties, a repeated key keeps its last value; in strict mode, see format.WithStrict,
+// repeated keys are rejected.
+type Properties struct {
+	// strict rejects repeated keys
+	strict bool
+}
+
+// Strict returns the formatter in strict mode.
+//
+// Returns:
+//
+//	format.Formatter: Properties formatter rejecting repeated keys
+func (Properties) Strict() format.Formatter {
+	return Properties{strict: true}
+}
+
+// Parse converts properties-formatted byte data into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The properties-formatted byte slice to be parsed
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if parsing fails (e.g., invalid escapes or conflicting keys)
+func (p Properties) Parse(data []byte) (*structpb.Struct, error) {
+	entries, err := parseEntries(string(data))
+	if err != nil {
+		return nil, err
+	}
+	root := make(map[string]any)
+	lines := make(map[string]int)
+	for _, entry := range entries {
+		if line, ok := lines[entry.key]; ok && p.strict {
+			return nil, fmt.Errorf("properties: line %d: key %q repeats line %d", entry.line, entry.key, line)
+		}
+		lines[entry.key] = entry.line
+		path, err := parseKey(entry.key)
+		if err != nil {
+			return nil, fmt.Errorf("properties: line %d: %w", entry.line, err)
+		}
+		if err := insert(root, path, entry.value); err != nil {
+			return nil, fmt.Errorf("properties: line %d: key %q: %w", entry.line, entry.key, err)
+		}
+	}
+	v, err := finalize(root)
+	if err != nil {
+		return nil, fmt.Errorf("properties: %w", err)
+	}
+	return structpb.NewStruct(v.(map[string]any))
+}
+
+// entry is a single key/value pair and the line it starts on
+type entry struct {
+	key   string
+	value string
+	line  int
+}
+
+// parseEntries splits the data into logical lines and parses them into key/value pairs
+func parseEntries(data string) ([]entry, error) {
+	var entries []entry
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if i == 0 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// Join continuation lines, an odd number of trailing backslashes continues the line
+		for continues(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continues(line) {
+			line = line[:len(line)-1]
+		}
+		key, value, err := splitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("properties: line %d: %w", start, err)
+		}
+		entries = append(entries, entry{key: key, value: value, line: start})
+	}
+	return entries, nil
+}
+
+// continues reports whether a line ends with an unescaped backslash
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitLine splits a logical line into its unescaped key and value
+func splitLine(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			end = i
+			break
+		}
+	}
+	rawKey := line[:end]
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	key, err := unescape(rawKey)
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescape(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// unescape resolves the escape sequences of java.util.Properties
+func unescape(s string) (string, error) {
+	if !strings.ContainsRune(s, '\\') {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\uXXXX encoding")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uXXXX encoding")
+			}
+			i += 4
+			// Combine UTF-16 surrogate pairs
+			if r >= 0xD800 && r < 0xDC00 && i+7 <= len(s) && strings.HasPrefix(s[i+1:], "\\u") {
+				if low, err := strconv.ParseUint(s[i+3:i+7], 16, 32); err == nil && low >= 0xDC00 && low < 0xE000 {
+					r = (r-0xD800)<<10 + (low - 0xDC00) + 0x10000
+					i += 6
+				}
+			}
+			if !utf8.ValidRune(rune(r)) {
+				r = utf8.RuneError
+			}
+			sb.WriteRune(rune(r))
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// segment is a single step of a key path, either an object key or a list index
+type segment struct {
+	key   string
+	index int
+}
+
+// isIndex reports whether the segment is a list index
+func (s segment) isIndex() bool {
+	return s.index >= 0
+}
+
+// parseKey splits a key such as "servers[0].host" into path segments
+func parseKey(key string) ([]segment, error) {
+	var path []segment
+	for _, part := range strings.Split(key, ".") {
+		name, rest, indexed := strings.Cut(part, "[")
+		if name == "" {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		path = append(path, segment{key: name, index: -1})
+		if !indexed {
+			continue
+		}
+		// Parse one or more indices, e.g. "[0][1]"
+		rest = "[" + rest
+		for rest != "" {
+			if rest[0] != '[' {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index in key %q", key)
+			}
+			path = append(path, segment{index: index})
+			rest = rest[end+1:]
+		}
+	}
+	return path, nil
+}
+
+// insert stores value at path. Objects are map[string]any and lists are map[int]any until finalized
+func insert(root map[string]any, path []segment, value any) error {
+	var node any = root
+	for i, seg := range path {
+		last := i == len(path)-1
+		var child any
+		var exists bool
+		switch n := node.(type) {
+		case map[string]any:
+			if seg.isIndex() {
+				return fmt.Errorf("index used on an object")
+			}
+			child, exists = n[seg.key]
+		case map[int]any:
+			if !seg.isIndex() {
+				return fmt.Errorf("key used on a list")
+			}
+			child, exists = n[seg.index]
+		default:
+			return fmt.Errorf("conflicts with a value")
+		}
+		if last {
+			if exists {
+				switch child.(type) {
+				case map[string]any, map[int]any:
+					return fmt.Errorf("conflicts with nested keys")
+				}
+			}
+			set(node, seg, value)
+			return nil
+		}
+		if !exists {
+			if path[i+1].isIndex() {
+				child = make(map[int]any)
+			} else {
+				child = make(map[string]any)
+			}
+			set(node, seg, child)
+		}
+		node = child
+	}
+	return nil
+}
+
+// set stores value in an object or a list
+func set(node any, seg segment, value any) {
+	switch n := node.(type) {
+	case map[string]any:
+		n[seg.key] = value
+	case map[int]any:
+		n[seg.index] = value
+	}
+}
+
+// finalize converts the temporary list representation into slices
+func finalize(node any) (any, error) {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, child := range n {
+			v, err := finalize(child)
+			if err != nil {
+				return nil, err
+			}
+			n[key] = v
+		}
+		return n, nil
+	case map[int]any:
+		indices := make([]int, 0, len(n))
+		for index := range n {
+			indices = append(indices, index)
+		}
+		sort.Ints(indices)
+		list := make([]any, 0, len(indices))
+		for i, index := range indices {
+			if index != i {
+				return nil, fmt.Errorf("list index %d is missing", i)
+			}
+			v, err := finalize(n[index])
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	default:
+		return n, nil
+	}
+}
@@ -0,0 +1,144 @@
+package properties
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestParse_Success tests successful parsing of dotted and indexed keys.
+func TestParse_Success(t *testing.T) {
+	data := []byte(`# Spring Boot style configuration
+! another comment
+spring.redis.host=localhost
+spring.redis.port = 6379
+spring.redis.ssl: true
+servers[0].host=a.example.com
+servers[0].port=80
+servers[1].host=b.example.com
+tags[0]=blue
+tags[1]=green
+matrix[0][0]=x
+`)
+	parser := Properties{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"spring": map[string]interface{}{
+			"redis": map[string]interface{}{
+				"host": "localhost",
+				"port": "6379",
+				"ssl":  "true",
+			},
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a.example.com", "port": "80"},
+			map[string]interface{}{"host": "b.example.com"},
+		},
+		"tags":   []interface{}{"blue", "green"},
+		"matrix": []interface{}{[]interface{}{"x"}},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_Bool tests that boolean literals load into string fields as well as bool fields.
+func TestParse_Bool(t *testing.T) {
+	data := []byte("javaPackage=true\njava_multiple_files=TRUE\ndeprecated=false\n")
+	result, err := Properties{}.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	options := &descriptorpb.FileOptions{}
+	if err := format.StructToMessage(result, options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options.GetJavaPackage() != "true" || !options.GetJavaMultipleFiles() || options.Deprecated == nil || options.GetDeprecated() {
+		t.Errorf("Unexpected message %v", options)
+	}
+}
+
+// TestParse_Escapes tests escapes, unicode and line continuations.
+func TestParse_Escapes(t *testing.T) {
+	data := []byte("greeting=Hello\\tWorld\\n\n" +
+		"unicode=\\u4F60\\u597D\n" +
+		"emoji=\\uD83D\\uDE00\n" +
+		"key\\ with\\ spaces=value\n" +
+		"colon\\:key=value\n" +
+		"fruits=apple, \\\n" +
+		"        banana, \\\n" +
+		"        cherry\n" +
+		"path=C:\\\\dir\n" +
+		"empty=\n" +
+		"spaced   value with spaces\n")
+	parser := Properties{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"greeting":        "Hello\tWorld\n",
+		"unicode":         "你好",
+		"emoji":           "😀",
+		"key with spaces": "value",
+		"colon:key":       "value",
+		"fruits":          "apple, banana, cherry",
+		"path":            `C:\dir`,
+		"empty":           "",
+		"spaced":          "value with spaces",
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_InvalidProperties tests error handling for invalid properties.
+func TestParse_InvalidProperties(t *testing.T) {
+	tests := map[string]string{
+		"malformed unicode": "a=\\u12",
+		"value and parent":  "a=1\na.b=2",
+		"sparse list":       "a[1]=x",
+		"invalid index":     "a[x]=1",
+		"empty segment":     "a..b=1",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := Properties{}
+			result, err := parser.Parse([]byte(data))
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if result != nil {
+				t.Errorf("Expected nil result, got %v", result)
+			}
+		})
+	}
+}
+
+// TestParse_Strict tests that a repeated key keeps its last value, and is rejected in strict mode.
+func TestParse_Strict(t *testing.T) {
+	data := []byte("server.port=80\nserver.host=localhost\nserver.port=8080\n")
+	result, err := Properties{}.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedMap := map[string]interface{}{
+		"server": map[string]interface{}{"port": "8080", "host": "localhost"},
+	}
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+
+	if _, err := (Properties{}).Strict().Parse(data); err == nil {
+		t.Fatalf("Expected error for repeated key in strict mode, got nil")
+	}
+}
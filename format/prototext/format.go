@@ -0,0 +1,97 @@
+package prototext
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Prototext formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("txtpb", Prototext{})
+	format.RegisterFormatter("textproto", Prototext{})
+}
+
+// Prototext implements the Formatter and MessageFormatter interfaces for the protobuf text format.
+//
+// Data is decoded directly into the target message when loaded with gonfig.Load or gonfig.Watch.
+// Parse needs the message type, which is MessageName or the name in a "# proto-message: <name>"
+// header comment, and the type must be linked into the binary.
+type Prototext struct {
+	// MessageName is the full name of the message type used by Parse, e.g. "example.Config"
+	MessageName protoreflect.FullName
+}
+
+// Unmarshal decodes protobuf text data into a message.
+//
+// Args:
+//
+//	data ([]byte): The protobuf text data to be decoded
+//	message (proto.Message): Message the data is decoded into
+//
+// Returns:
+//
+//	error: An error if decoding fails
+func (Prototext) Unmarshal(data []byte, message proto.Message) error {
+	if err := prototext.Unmarshal(data, message); err != nil {
+		return fmt.Errorf("prototext: %w", err)
+	}
+	return nil
+}
+
+// Parse converts protobuf text data into a Protocol Buffer Struct object using the protojson mapping.
+//
+// Args:
+//
+//	data ([]byte): The protobuf text data to be parsed
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if the message type is unknown or parsing fails
+func (p Prototext) Parse(data []byte) (*structpb.Struct, error) {
+	name := p.MessageName
+	if name == "" {
+		name = messageName(data)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("prototext: message type is unknown, add a '# proto-message: <name>' header")
+	}
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("prototext: %w", err)
+	}
+	message := messageType.New().Interface()
+	if err := p.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	return format.StructFromMessage(message)
+}
+
+// messageName returns the message type declared by the "# proto-message:" header comment
+func messageName(data []byte) protoreflect.FullName {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		comment, ok := strings.CutPrefix(line, "#")
+		if !ok {
+			// The header precedes the first field
+			return ""
+		}
+		if name, ok := strings.CutPrefix(strings.TrimSpace(comment), "proto-message:"); ok {
+			return protoreflect.FullName(strings.TrimSpace(name))
+		}
+	}
+	return ""
+}
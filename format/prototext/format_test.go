@@ -0,0 +1,59 @@
+package prototext
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyacen/gonfig/test"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestUnmarshal_Success tests successful decoding of protobuf text data into a message.
+func TestUnmarshal_Success(t *testing.T) {
+	data := []byte(`field1: "a" field2: "b"`)
+	config := &test.Config{}
+	if err := (Prototext{}).Unmarshal(data, config); err != nil {
+		t.Fatal(err)
+	}
+	expected := &test.Config{Field1: "a", Field2: "b"}
+	if !proto.Equal(config, expected) {
+		t.Errorf("expected %v; got %v", expected, config)
+	}
+}
+
+// TestParse_Success tests successful parsing of protobuf text data with a known message type.
+func TestParse_Success(t *testing.T) {
+	expected := map[string]any{"field1": "a"}
+
+	name := (&test.Config{}).ProtoReflect().Descriptor().FullName()
+	s, err := Prototext{}.Parse([]byte("# proto-file: test/conf.proto\n# proto-message: " + string(name) + "\n\nfield1: \"a\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+
+	s, err = Prototext{MessageName: name}.Parse([]byte(`field1: "a"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, s.AsMap())
+	}
+}
+
+// TestParse_InvalidPrototext tests error handling for invalid data and unknown message types.
+func TestParse_InvalidPrototext(t *testing.T) {
+	name := (&test.Config{}).ProtoReflect().Descriptor().FullName()
+	cases := map[string]Prototext{
+		"unknown type":  {},
+		"missing type":  {MessageName: name.Parent().Append("Missing")},
+		"unknown field": {MessageName: name},
+	}
+	for name, formatter := range cases {
+		if _, err := formatter.Parse([]byte(`field3: "a"`)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
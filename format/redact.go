@@ -0,0 +1,115 @@
+package format
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Sensitive holds the values of the sensitive fields of a configuration, e.g. decrypted secrets,
+// so that errors converting them into messages do not contain them.
+// It is per-load state: the caller of a transformer attaches a Sensitive to the context with ContextWithSensitive,
+// the transformer marks the values it produces with MarkSensitive, and the caller redacts conversion errors
+// of the transformed data with Redact. Only the latest value of every path is kept.
+// The zero value is ready to use, and a nil Sensitive marks and redacts nothing.
+type Sensitive struct {
+	// mutex protects tokens
+	mutex sync.Mutex
+	// tokens are the encoded values of the sensitive fields by path
+	tokens map[string]string
+}
+
+// Mark marks the value of a field as sensitive, replacing the value previously marked for the path.
+//
+// Args:
+//
+//	path (string): Path of the field, e.g. "redis.password" or "tokens[0]"
+//	value (*structpb.Value): Value of the field
+func (s *Sensitive) Mark(path string, value *structpb.Value) {
+	if s == nil {
+		return
+	}
+	token := encodeValue(value)
+	if token == "" {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[string]string)
+	}
+	s.tokens[path] = token
+}
+
+// Redact replaces the value of the field of a conversion error by "[redacted]" if it is marked as sensitive.
+// protojson quotes the offending JSON token, which is the encoded value of the field.
+//
+// Args:
+//
+//	err (error): Error returned by StructToMessage, other errors are returned as is
+//
+// Returns:
+//
+//	error: err with the sensitive value redacted
+func (s *Sensitive) Redact(err error) error {
+	var convertErr *ConvertError
+	if s == nil || !errors.As(err, &convertErr) {
+		return err
+	}
+	s.mutex.Lock()
+	token, ok := s.tokens[convertErr.Path]
+	s.mutex.Unlock()
+	if !ok {
+		return err
+	}
+	pattern := regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(token) + `($|[^\w.])`)
+	convertErr.Err = errors.New(pattern.ReplaceAllString(errorMessage(convertErr.Err), "${1}[redacted]${2}"))
+	return err
+}
+
+// sensitiveKey is the context key of the Sensitive sets values are marked in
+type sensitiveKey struct{}
+
+// ContextWithSensitive returns a context marking the values passed to MarkSensitive in sensitive,
+// in addition to the sets of the parent context.
+//
+// Args:
+//
+//	ctx (context.Context): Parent context
+//	sensitive (*Sensitive): Set of sensitive values of the load
+//
+// Returns:
+//
+//	context.Context: Context passed to the transformers
+func ContextWithSensitive(ctx context.Context, sensitive *Sensitive) context.Context {
+	parents, _ := ctx.Value(sensitiveKey{}).([]*Sensitive)
+	return context.WithValue(ctx, sensitiveKey{}, append(slices.Clip(parents), sensitive))
+}
+
+// MarkSensitive marks the value of a field as sensitive in the sets attached to the context, see Sensitive.
+// Transformers producing secrets, such as the decrypter of the crypt package, call it for every value they produce.
+//
+// Args:
+//
+//	ctx (context.Context): Context the transformer is called with
+//	path (string): Path of the field, e.g. "redis.password" or "tokens[0]"
+//	value (*structpb.Value): Value of the field
+func MarkSensitive(ctx context.Context, path string, value *structpb.Value) {
+	sets, _ := ctx.Value(sensitiveKey{}).([]*Sensitive)
+	for _, sensitive := range sets {
+		sensitive.Mark(path, value)
+	}
+}
+
+// encodeValue encodes a value as StructToMessage does, empty if it cannot be encoded
+func encodeValue(value *structpb.Value) string {
+	encoder := &spanEncoder{}
+	if err := encoder.encodeValue("", value, nil, false); err != nil {
+		return ""
+	}
+	return encoder.buf.String()
+}
@@ -0,0 +1,85 @@
+package format
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultRegistry is the registry used by RegisterFormatter and GetFormatter,
+// and by resources unless another registry is given.
+// The format packages register their formatters in it when imported.
+var DefaultRegistry = NewRegistry()
+
+// Registry maps file extensions to formatters.
+// Separate registries let libraries and tests register formatters without affecting each other.
+type Registry struct {
+	// mutex protects formatters
+	mutex sync.RWMutex
+	// formatters stores registered format parsers by lower-case extension
+	formatters map[string]Formatter
+}
+
+// NewRegistry creates an empty registry
+//
+// Returns:
+//
+//	*Registry: New registry
+func NewRegistry() *Registry {
+	return &Registry{formatters: make(map[string]Formatter)}
+}
+
+// Register associates a file extension with a configuration parser
+//
+// Args:
+//
+//	ext (string): File extension (e.g., "yaml", "toml")
+//	formatter (Formatter): Implementation of the Formatter interface
+//	opts (...Option): Options applied to the formatter, e.g. WithStrict
+//
+// Returns:
+//
+//	error: Error if an option does not apply to the formatter, nothing is registered then
+func (r *Registry) Register(ext string, formatter Formatter, opts ...Option) error {
+	for _, opt := range opts {
+		var err error
+		if formatter, err = opt(formatter); err != nil {
+			return err
+		}
+	}
+	r.mutex.Lock()
+	r.formatters[strings.ToLower(ext)] = formatter
+	r.mutex.Unlock()
+	return nil
+}
+
+// Get retrieves the parser associated with a specific file extension
+//
+// Args:
+//
+//	ext (string): File extension to look up
+//
+// Returns:
+//
+//	Formatter: Registered parser or nil if not found
+//	bool: Whether a parser is registered
+func (r *Registry) Get(ext string) (Formatter, bool) {
+	r.mutex.RLock()
+	formatter, ok := r.formatters[strings.ToLower(ext)]
+	r.mutex.RUnlock()
+	return formatter, ok
+}
+
+// Clone creates a registry with the same registrations, e.g. to override some formatters of DefaultRegistry
+//
+// Returns:
+//
+//	*Registry: New registry
+func (r *Registry) Clone() *Registry {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	clone := NewRegistry()
+	for ext, formatter := range r.formatters {
+		clone.formatters[ext] = formatter
+	}
+	return clone
+}
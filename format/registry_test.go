@@ -0,0 +1,101 @@
+package format_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/json"
+	"github.com/soyacen/gonfig/format/properties"
+)
+
+// apply applies an option to a formatter
+func apply(t *testing.T, formatter format.Formatter, opt format.Option) format.Formatter {
+	t.Helper()
+	formatter, err := opt(formatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return formatter
+}
+
+// TestRegistry tests that registries are isolated from each other and from DefaultRegistry.
+func TestRegistry(t *testing.T) {
+	registry := format.NewRegistry()
+	if _, ok := registry.Get("json"); ok {
+		t.Fatalf("expected an empty registry")
+	}
+	registry.Register("CONF", properties.Properties{})
+	if _, ok := registry.Get("conf"); !ok {
+		t.Fatalf("expected a formatter for conf")
+	}
+	if _, ok := format.GetFormatter("conf"); ok {
+		t.Fatalf("expected no formatter for conf in the default registry")
+	}
+
+	clone := format.DefaultRegistry.Clone()
+	clone.Register("json", properties.Properties{})
+	if formatter, _ := format.GetFormatter("json"); !reflect.DeepEqual(formatter, json.Json{}) {
+		t.Fatalf("expected the default registry to keep its json formatter; got %T", formatter)
+	}
+	if _, ok := clone.Get("yaml"); !ok {
+		t.Fatalf("expected the clone to keep the yaml formatter")
+	}
+}
+
+// TestWithStrict tests registration of formatters in strict mode.
+func TestWithStrict(t *testing.T) {
+	registry := format.NewRegistry()
+	if err := registry.Register("properties", properties.Properties{}, format.WithStrict()); err != nil {
+		t.Fatalf("expected no error; got %v", err)
+	}
+
+	formatter, _ := registry.Get("properties")
+	if _, err := formatter.Parse([]byte("port=80\nport=8080\n")); err == nil {
+		t.Errorf("expected error for repeated key")
+	}
+	if _, err := formatter.Parse([]byte("port=80\n")); err != nil {
+		t.Errorf("expected no error; got %v", err)
+	}
+
+	// Json has no strict mode, the setting must not be ignored silently
+	if err := registry.Register("json", json.Json{}, format.WithStrict()); err == nil || !strings.Contains(err.Error(), "does not support strict mode") {
+		t.Errorf("expected error for formatter without strict mode; got %v", err)
+	}
+	if _, ok := registry.Get("json"); ok {
+		t.Errorf("expected the formatter without strict mode not to be registered")
+	}
+}
+
+// TestWithKeyCase tests conversion of the keys of parsed data.
+func TestWithKeyCase(t *testing.T) {
+	registry := format.NewRegistry()
+	registry.Register("json", json.Json{}, format.WithKeyCase(format.SnakeCase))
+	formatter, _ := registry.Get("json")
+	value, err := formatter.Parse([]byte(`{"maxConns": 10, "HTTPServer": {"read-timeout": "1s"}, "servers": [{"hostName": "a"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"max_conns":   float64(10),
+		"http_server": map[string]any{"read_timeout": "1s"},
+		"servers":     []any{map[string]any{"host_name": "a"}},
+	}
+	if !reflect.DeepEqual(expected, value.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, value.AsMap())
+	}
+
+	tests := map[string]string{
+		"max_conns":  "maxConns",
+		"max-conns":  "maxConns",
+		"MaxConns":   "maxConns",
+		"_private":   "private",
+		"already_ok": "alreadyOk",
+	}
+	for key, expected := range tests {
+		if got := format.LowerCamelCase(key); got != expected {
+			t.Errorf("LowerCamelCase(%q): expected %q; got %q", key, expected, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// sniffFormats are the formats tried by Sniff, in order.
+// Stricter formats come first: JSON is also YAML and almost anything is valid Java properties.
+var sniffFormats = []string{"json", "toml", "yaml", "properties"}
+
+// Sniff detects the format of data by parsing it with the formatters of DefaultRegistry.
+// See Registry.Sniff.
+//
+// Args:
+//
+//	data ([]byte): Raw configuration data
+//
+// Returns:
+//
+//	string: Name of the detected format, e.g. "yaml"
+//	bool: Whether a format was detected
+func Sniff(data []byte) (string, bool) {
+	return DefaultRegistry.Sniff(data)
+}
+
+// Sniff detects the format of data by parsing it with the registered formatters of
+// JSON, TOML, YAML and Java properties, in this order, and returns the first one that succeeds.
+// JSON is only tried for data starting with '{', and a leading byte order mark is ignored.
+//
+// Args:
+//
+//	data ([]byte): Raw configuration data
+//
+// Returns:
+//
+//	string: Name of the detected format, e.g. "yaml"
+//	bool: Whether a format was detected
+func (r *Registry) Sniff(data []byte) (string, bool) {
+	ext, _, err := r.sniff(data)
+	return ext, err == nil
+}
+
+// sniff returns the name of the detected format and the parsed data
+func (r *Registry) sniff(data []byte) (string, *structpb.Struct, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	for _, ext := range sniffFormats {
+		if ext == "json" && !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			continue
+		}
+		formatter, ok := r.Get(ext)
+		if !ok {
+			continue
+		}
+		if value, err := formatter.Parse(data); err == nil {
+			return ext, value, nil
+		}
+	}
+	return "", nil, errors.New("format: unable to detect the format of the data")
+}
+
+// Sniffer is a Formatter detecting the format of the data with Registry.Sniff on every Parse.
+// It is used by resources whose name has no extension and no format is set explicitly.
+type Sniffer struct {
+	// Registry provides the formatters, DefaultRegistry if nil
+	Registry *Registry
+}
+
+// Parse detects the format of the data and parses it with the corresponding formatter.
+//
+// Args:
+//
+//	data ([]byte): Raw configuration data
+//
+// Returns:
+//
+//	*structpb.Struct: Parsed structured data
+//	error: Error if no format parses the data
+func (s Sniffer) Parse(data []byte) (*structpb.Struct, error) {
+	registry := s.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	_, value, err := registry.sniff(data)
+	return value, err
+}
+
+// Locate detects the format of the data and locates the field with the corresponding formatter if it is a Locator
+//
+// Args:
+//
+//	data ([]byte): Raw configuration data
+//	path (string): Field path
+//
+// Returns:
+//
+//	Position: Position of the field
+//	bool: Whether the field was found
+func (s Sniffer) Locate(data []byte, path string) (Position, bool) {
+	registry := s.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	ext, _, err := registry.sniff(data)
+	if err != nil {
+		return Position{}, false
+	}
+	formatter, _ := registry.Get(ext)
+	if locator, ok := formatter.(Locator); ok {
+		return locator.Locate(bytes.TrimPrefix(data, []byte("\ufeff")), path)
+	}
+	return Position{}, false
+}
@@ -0,0 +1,47 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	_ "github.com/soyacen/gonfig/format/json"
+	_ "github.com/soyacen/gonfig/format/properties"
+	_ "github.com/soyacen/gonfig/format/toml"
+	_ "github.com/soyacen/gonfig/format/yaml"
+)
+
+// TestSniff tests detection of the format of data.
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"JSON", `{"server": {"port": 8080}}`, "json"},
+		{"JSON with BOM", "\ufeff {\"key\": \"value\"}", "json"},
+		{"TOML", "[server]\nport = 8080\n", "toml"},
+		{"TOML key", "name = \"app\"\n", "toml"},
+		{"YAML", "server:\n  port: 8080\n", "yaml"},
+		{"YAML flow mapping", "{server: {port: 8080}}", "yaml"},
+		{"Properties", "server.port=8080\nserver.addr=0.0.0.0\n", "properties"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := format.Sniff([]byte(tt.data))
+			if !ok || got != tt.expected {
+				t.Errorf("expected %q; got %q (%v)", tt.expected, got, ok)
+			}
+		})
+	}
+}
+
+// TestSniffer_Parse tests parsing of data in a detected format.
+func TestSniffer_Parse(t *testing.T) {
+	value, err := format.Sniffer{}.Parse([]byte("server:\n  port: 8080\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := value.GetFields()["server"].GetStructValue().GetFields()["port"].GetNumberValue(); port != 8080 {
+		t.Errorf("expected 8080; got %v", port)
+	}
+}
@@ -0,0 +1,203 @@
+package format
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxCachedTemplates bounds the number of compiled templates kept by a formatter
+const maxCachedTemplates = 64
+
+// TemplateFuncs returns the functions available to templates rendered with WithTemplate:
+//   - env "NAME" returns the value of an environment variable, empty if unset
+//   - hostname returns the host name
+//   - default DEFAULT VALUE returns VALUE, or DEFAULT if VALUE is empty, e.g. {{ env "PORT" | default "8080" }}
+//   - b64enc and b64dec encode and decode standard base64
+//
+// Returns:
+//
+//	template.FuncMap: Default template functions
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"env": os.Getenv,
+		"hostname": func() (string, error) {
+			return os.Hostname()
+		},
+		"default": func(def any, value any) any {
+			switch v := value.(type) {
+			case nil:
+				return def
+			case string:
+				if v == "" {
+					return def
+				}
+			}
+			return value
+		},
+		"b64enc": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"b64dec": func(s string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(s)
+			return string(data), err
+		},
+	}
+}
+
+// WithTemplate renders data as a text/template before it is parsed, e.g.
+//
+//	addr: {{ env "REDIS_HOST" | default "localhost" }}:6379
+//
+// The functions of TemplateFuncs are available, funcs adds functions or replaces them.
+// Templates are executed with the environment variables as .Env, e.g. {{ .Env.HOME }}.
+// Compiled templates are cached by content, so unchanged data is not parsed again when it is rendered again.
+// Data is rendered whenever it is parsed: the resources parse it on every load and, while watching, only when
+// the raw data changes, so a change of the environment variables alone is not noticed by Watch.
+//
+// Args:
+//
+//	funcs (template.FuncMap): Additional template functions, may be nil
+//
+// Returns:
+//
+//	Option: Option for Register
+func WithTemplate(funcs template.FuncMap) Option {
+	merged := TemplateFuncs()
+	for name, fn := range funcs {
+		merged[name] = fn
+	}
+	return func(formatter Formatter) (Formatter, error) {
+		cache := &templateCache{templates: make(map[[sha256.Size]byte]*template.Template)}
+		return newTemplateFormatter(formatter, merged, "config", cache), nil
+	}
+}
+
+// newTemplateFormatter creates a templateFormatter, keeping the Unmarshal method of a MessageFormatter
+func newTemplateFormatter(formatter Formatter, funcs template.FuncMap, name string, cache *templateCache) Formatter {
+	f := &templateFormatter{formatter: formatter, funcs: funcs, name: name, cache: cache}
+	if _, ok := formatter.(MessageFormatter); ok {
+		return &templateMessageFormatter{templateFormatter: f}
+	}
+	return f
+}
+
+// templateFormatter renders data as a template before parsing it with a formatter
+type templateFormatter struct {
+	formatter Formatter
+	funcs     template.FuncMap
+	// name identifies the source in template errors
+	name string
+	// cache is shared by the formatters bound to different sources
+	cache *templateCache
+}
+
+// templateCache holds compiled templates by the hash of their name and text
+type templateCache struct {
+	mutex     sync.Mutex
+	templates map[[sha256.Size]byte]*template.Template
+}
+
+// Parse renders data and parses the result with the formatter
+func (f *templateFormatter) Parse(data []byte) (*structpb.Struct, error) {
+	rendered, err := f.render(data)
+	if err != nil {
+		return nil, err
+	}
+	return f.formatter.Parse(rendered)
+}
+
+// WithSource binds the formatter to a source, which names the template in errors
+func (f *templateFormatter) WithSource(name string) Formatter {
+	formatter := f.formatter
+	if sourceFormatter, ok := formatter.(SourceFormatter); ok {
+		formatter = sourceFormatter.WithSource(name)
+	}
+	return newTemplateFormatter(formatter, f.funcs, name, f.cache)
+}
+
+// Dependencies returns the dependencies of the formatter if it is a DependentFormatter
+func (f *templateFormatter) Dependencies() []string {
+	if dependentFormatter, ok := f.formatter.(DependentFormatter); ok {
+		return dependentFormatter.Dependencies()
+	}
+	return nil
+}
+
+// Locate renders data and locates the field in the result with the formatter if it is a Locator
+func (f *templateFormatter) Locate(data []byte, path string) (Position, bool) {
+	locator, ok := f.formatter.(Locator)
+	if !ok {
+		return Position{}, false
+	}
+	rendered, err := f.render(data)
+	if err != nil {
+		return Position{}, false
+	}
+	return locator.Locate(rendered, path)
+}
+
+// render executes data as a template
+func (f *templateFormatter) render(data []byte) ([]byte, error) {
+	tmpl, err := f.compile(data)
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]any{"Env": env}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compile returns the compiled template of data, from the cache if it has been compiled before
+func (f *templateFormatter) compile(data []byte) (*template.Template, error) {
+	hash := sha256.New()
+	hash.Write([]byte(f.name))
+	hash.Write([]byte{0})
+	hash.Write(data)
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+
+	f.cache.mutex.Lock()
+	defer f.cache.mutex.Unlock()
+	if tmpl, ok := f.cache.templates[key]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New(f.name).Funcs(f.funcs).Option("missingkey=zero").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(f.cache.templates) >= maxCachedTemplates {
+		clear(f.cache.templates)
+	}
+	f.cache.templates[key] = tmpl
+	return tmpl, nil
+}
+
+// templateMessageFormatter is a templateFormatter keeping the Unmarshal method of a MessageFormatter
+type templateMessageFormatter struct {
+	*templateFormatter
+}
+
+// Unmarshal renders data and decodes the result into a message with the formatter
+func (f *templateMessageFormatter) Unmarshal(data []byte, message proto.Message) error {
+	rendered, err := f.render(data)
+	if err != nil {
+		return err
+	}
+	return f.formatter.(MessageFormatter).Unmarshal(rendered, message)
+}
@@ -0,0 +1,68 @@
+package format_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/json"
+	"github.com/soyacen/gonfig/format/prototext"
+	"github.com/soyacen/gonfig/format/yaml"
+)
+
+// TestWithTemplate tests rendering of data with the default and additional template functions.
+func TestWithTemplate(t *testing.T) {
+	t.Setenv("GONFIG_TEST_HOST", "redis.local")
+	t.Setenv("GONFIG_TEST_EMPTY", "")
+	formatter := apply(t, yaml.Yaml{}, format.WithTemplate(template.FuncMap{"upper": strings.ToUpper}))
+	data := []byte(`
+host: {{ env "GONFIG_TEST_HOST" }}
+port: {{ env "GONFIG_TEST_EMPTY" | default "6379" }}
+home: {{ .Env.GONFIG_TEST_HOST | upper }}
+token: {{ "s3cret" | b64enc }}
+plain: {{ "czNjcmV0" | b64dec }}
+missing: "{{ .Env.GONFIG_TEST_UNSET }}"
+`)
+	value, err := formatter.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"host":    "redis.local",
+		"port":    float64(6379),
+		"home":    "REDIS.LOCAL",
+		"token":   "czNjcmV0",
+		"plain":   "s3cret",
+		"missing": "",
+	}
+	if !reflect.DeepEqual(expected, value.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, value.AsMap())
+	}
+
+	// The compiled template is reused, the environment is read on every render
+	t.Setenv("GONFIG_TEST_HOST", "redis.remote")
+	value, err = formatter.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["host"].GetStringValue(); got != "redis.remote" {
+		t.Errorf("expected redis.remote; got %q", got)
+	}
+}
+
+// TestWithTemplate_Invalid tests that template errors name the source.
+func TestWithTemplate_Invalid(t *testing.T) {
+	if _, ok := apply(t, prototext.Prototext{}, format.WithTemplate(nil)).(format.MessageFormatter); !ok {
+		t.Errorf("expected the formatter to keep Unmarshal")
+	}
+	formatter := apply(t, json.Json{}, format.WithTemplate(nil)).(format.SourceFormatter).WithSource("app.json")
+	_, err := formatter.Parse([]byte(`{"key": "{{ .Env.HOME"}`))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "app.json") {
+		t.Errorf("expected error naming the source; got %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package toml
 
 import (
+	"errors"
+	"time"
+
 	"github.com/BurntSushi/toml"
 	"github.com/soyacen/gonfig/format"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -12,6 +15,14 @@ func init() {
 }
 
 // Toml implements the Formatter interface for environment variables format.
+//
+// Temporal values become RFC 3339 strings, as timestamps of the YAML formatter:
+//   - offset date-times keep their offset, e.g. "1979-05-27T07:32:00-08:00"
+//   - local date-times have no offset and are assumed to be UTC, e.g. "1979-05-27T07:32:00Z"
+//   - local dates are assumed to be midnight UTC, e.g. "1979-05-27T00:00:00Z" like the YAML date 1979-05-27
+//   - local times become partial-time strings, e.g. "07:32:00.5", as they have no date
+//
+// Dates and date-times therefore all map onto google.protobuf.Timestamp fields.
 type Toml struct{}
 
 // Parse converts TOML-formatted byte data into a Protocol Buffer Struct object.
@@ -27,7 +38,49 @@ type Toml struct{}
 func (Toml) Parse(data []byte) (*structpb.Struct, error) {
 	v := make(map[string]any)
 	if err := toml.Unmarshal(data, &v); err != nil {
-		return nil, err
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &format.ParseError{
+				Position: format.Position{Line: parseErr.Position.Line, Column: parseErr.Position.Col},
+				Path:     parseErr.LastKey,
+				Err:      errors.New("toml: " + parseErr.Message),
+			}
+		}
+		return nil, &format.ParseError{Err: err}
+	}
+	return structpb.NewStruct(convert(v).(map[string]any))
+}
+
+// convert replaces temporal values with RFC 3339 strings, recursively
+func convert(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = convert(value)
+		}
+		return v
+	case []map[string]any:
+		list := make([]any, len(v))
+		for i, value := range v {
+			list[i] = convert(value)
+		}
+		return list
+	case []any:
+		for i, value := range v {
+			v[i] = convert(value)
+		}
+		return v
+	case time.Time:
+		// The decoder marks local values with fixed zones of these names
+		switch v.Location().String() {
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		case "date-local", "datetime-local":
+			return time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC).Format(time.RFC3339Nano)
+		default:
+			return v.Format(time.RFC3339Nano)
+		}
+	default:
+		return v
 	}
-	return structpb.NewStruct(v)
 }
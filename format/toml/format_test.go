@@ -1,8 +1,16 @@
 package toml
 
 import (
+	"errors"
 	"reflect"
+	"strconv"
 	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/yaml"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // TestParse_Success tests successful parsing of valid TOML data.
@@ -42,3 +50,85 @@ func TestParse_InvalidToml(t *testing.T) {
 		t.Errorf("Expected nil result, got %v", result)
 	}
 }
+
+// TestParse_Datetime tests conversion of every kind of TOML temporal value into RFC 3339 strings.
+func TestParse_Datetime(t *testing.T) {
+	data := []byte(`
+offset = 1979-05-27T00:32:00.999999-07:00
+utc = 1979-05-27T07:32:00Z
+local_datetime = 1979-05-27T07:32:00
+local_date = 1979-05-27
+local_time = 07:32:00.5
+list = [1979-05-27]
+
+[[events]]
+at = 1979-05-27T07:32:00Z
+`)
+	parser := Toml{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"offset":         "1979-05-27T00:32:00.999999-07:00",
+		"utc":            "1979-05-27T07:32:00Z",
+		"local_datetime": "1979-05-27T07:32:00Z",
+		"local_date":     "1979-05-27T00:00:00Z",
+		"local_time":     "07:32:00.5",
+		"list":           []interface{}{"1979-05-27T00:00:00Z"},
+		"events":         []interface{}{map[string]interface{}{"at": "1979-05-27T07:32:00Z"}},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+
+	// Dates and date-times map onto google.protobuf.Timestamp
+	for _, key := range []string{"offset", "utc", "local_datetime", "local_date"} {
+		value := result.GetFields()[key].GetStringValue()
+		if err := protojson.Unmarshal([]byte(strconv.Quote(value)), &timestamppb.Timestamp{}); err != nil {
+			t.Errorf("Expected %s to be a valid Timestamp, got %v", key, err)
+		}
+	}
+}
+
+// TestParse_LocalLikeYaml tests that local dates and date-times are converted like the timestamps of the YAML formatter.
+func TestParse_LocalLikeYaml(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		yaml string
+	}{
+		{"Local Date", "at = 1979-05-27", "at: 1979-05-27"},
+		{"Local Datetime", "at = 1979-05-27T07:32:00.5", "at: 1979-05-27 07:32:00.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tomlResult, err := Toml{}.Parse([]byte(tt.toml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			yamlResult, err := yaml.Yaml{}.Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(yamlResult.AsMap(), tomlResult.AsMap()) {
+				t.Errorf("Expected %v like YAML, got %v", yamlResult.AsMap(), tomlResult.AsMap())
+			}
+		})
+	}
+}
+
+// TestParse_ErrorPosition tests that syntax errors report their line, column and last key.
+func TestParse_ErrorPosition(t *testing.T) {
+	data := []byte("[redis]\naddr = \"localhost\"\nport = invalid_value\n")
+	_, err := Toml{}.Parse(data)
+	var parseErr *format.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *format.ParseError, got %v", err)
+	}
+	if parseErr.Line != 3 || parseErr.Column == 0 || parseErr.Path != "redis.port" {
+		t.Errorf("Expected an error at line 3 of redis.port, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// init registers the Xml formatter with the global format registry.
+func init() {
+	format.RegisterFormatter("xml", Xml{})
+}
+
+// Xml implements the Formatter interface for XML format.
+//
+// XML documents are converted with the following rules:
+//   - The root element is unwrapped, its attributes and children become the top-level keys.
+//   - An element with attributes or child elements becomes an object. Attributes become keys
+//     named AttributePrefix + attribute name, child elements become keys named after the element.
+//   - Child elements repeated with the same name become a list in source order.
+//   - An element with only text becomes its trimmed text, an empty element becomes null.
+//   - Text of an element that also has attributes or children is stored under TextKey.
+//     Whitespace-only text is ignored, CDATA sections are treated as text, comments and
+//     processing instructions are ignored.
+//   - Values stay strings, format.StructToMessage converts them for numeric and bool fields,
+//     e.g. <debug>true</debug> sets a bool field and a string field alike.
+//   - Namespace prefixes are dropped (<c:port> becomes "port") unless KeepNamespacePrefix is set
+//     (<c:port> becomes "c:port"). Namespace declarations (xmlns attributes) are ignored.
+//   - An attribute and a child element that map to the same key are an error.
+//
+// The zero value is registered for the "xml" extension. Register a configured value to use
+// another convention, e.g. format.RegisterFormatter("xml", xml.Xml{AttributePrefix: "@"}).
+type Xml struct {
+	// AttributePrefix is prepended to attribute names, e.g. "@" or "-". Empty by default so that
+	// attributes map directly onto fields.
+	AttributePrefix string
+	// TextKey is the key holding the text of elements that also have attributes or children,
+	// "#text" if empty.
+	TextKey string
+	// KeepNamespacePrefix keeps namespace prefixes in keys.
+	KeepNamespacePrefix bool
+}
+
+// Parse converts XML-formatted byte data into a Protocol Buffer Struct object.
+//
+// Args:
+//
+//	data ([]byte): The XML-formatted byte slice to be parsed
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if parsing fails (e.g., malformed XML or conflicting keys)
+func (x Xml) Parse(data []byte) (*structpb.Struct, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*element
+	var root map[string]any
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 && root != nil {
+				return nil, fmt.Errorf("xml: multiple root elements")
+			}
+			stack = append(stack, x.newElement(t))
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("xml: unexpected end element </%s>", t.Name.Local)
+			}
+			current := stack[len(stack)-1]
+			if current.start.Name != t.Name {
+				return nil, fmt.Errorf("xml: element <%s> closed by </%s>", current.start.Name.Local, t.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+			value, err := x.convert(current)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) == 0 {
+				// The root element is unwrapped
+				switch v := value.(type) {
+				case map[string]any:
+					root = v
+				case nil:
+					root = map[string]any{}
+				default:
+					return nil, fmt.Errorf("xml: root element <%s> has only text", current.start.Name.Local)
+				}
+				continue
+			}
+			stack[len(stack)-1].addChild(x.key(t.Name), value)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, fmt.Errorf("xml: text outside of root element")
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("xml: element <%s> is not closed", stack[len(stack)-1].start.Name.Local)
+	}
+	if root == nil {
+		return nil, fmt.Errorf("xml: no root element")
+	}
+	return structpb.NewStruct(root)
+}
+
+// element is an element being decoded
+type element struct {
+	// start is the start token of the element
+	start xml.StartElement
+	// keys are the keys of children in first-seen order
+	keys []string
+	// children holds the values of child elements by key
+	children map[string][]any
+	// text accumulates the character data of the element
+	text bytes.Buffer
+}
+
+// addChild appends the value of a child element
+func (e *element) addChild(key string, value any) {
+	if _, ok := e.children[key]; !ok {
+		e.keys = append(e.keys, key)
+	}
+	e.children[key] = append(e.children[key], value)
+}
+
+// newElement creates an element for a start token
+func (x Xml) newElement(start xml.StartElement) *element {
+	return &element{start: start, children: make(map[string][]any)}
+}
+
+// key returns the key of an element or attribute name
+func (x Xml) key(name xml.Name) string {
+	if x.KeepNamespacePrefix && name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// convert converts a decoded element into a value accepted by structpb.NewValue
+func (x Xml) convert(e *element) (any, error) {
+	text := strings.TrimSpace(e.text.String())
+	var attrs []xml.Attr
+	for _, attr := range e.start.Attr {
+		// Namespace declarations are ignored
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	if len(attrs) == 0 && len(e.keys) == 0 {
+		if text == "" {
+			return nil, nil
+		}
+		return text, nil
+	}
+
+	v := make(map[string]any, len(attrs)+len(e.keys)+1)
+	for _, attr := range attrs {
+		v[x.AttributePrefix+x.key(attr.Name)] = attr.Value
+	}
+	for _, key := range e.keys {
+		if _, ok := v[key]; ok {
+			return nil, fmt.Errorf("xml: element <%s>: child <%s> conflicts with attribute of the same name", e.start.Name.Local, key)
+		}
+		values := e.children[key]
+		if len(values) == 1 {
+			v[key] = values[0]
+			continue
+		}
+		v[key] = values
+	}
+	if text != "" {
+		textKey := x.TextKey
+		if textKey == "" {
+			textKey = "#text"
+		}
+		if _, ok := v[textKey]; ok {
+			return nil, fmt.Errorf("xml: element <%s>: text conflicts with key %q", e.start.Name.Local, textKey)
+		}
+		v[textKey] = text
+	}
+	return v, nil
+}
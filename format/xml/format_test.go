@@ -0,0 +1,116 @@
+package xml
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestParse_Success tests successful parsing of elements, attributes and repeated elements.
+func TestParse_Success(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- vendor configuration -->
+<config xmlns="urn:vendor" xmlns:c="urn:vendor:common">
+  <name>Alice</name>
+  <debug>true</debug>
+  <server addr="0.0.0.0" port="8080">
+    <c:timeout>5s</c:timeout>
+  </server>
+  <host>a.example.com</host>
+  <host>b.example.com</host>
+  <label lang="en">Hello</label>
+  <script><![CDATA[if (a < b) {}]]></script>
+  <empty/>
+</config>`)
+	parser := Xml{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"name":  "Alice",
+		"debug": "true",
+		"server": map[string]interface{}{
+			"addr":    "0.0.0.0",
+			"port":    "8080",
+			"timeout": "5s",
+		},
+		"host": []interface{}{"a.example.com", "b.example.com"},
+		"label": map[string]interface{}{
+			"lang":  "en",
+			"#text": "Hello",
+		},
+		"script": "if (a < b) {}",
+		"empty":  nil,
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_Bool tests that boolean literals load into string fields as well as bool fields.
+func TestParse_Bool(t *testing.T) {
+	data := []byte(`<options java_multiple_files="TRUE"><javaPackage>true</javaPackage><deprecated>false</deprecated></options>`)
+	result, err := Xml{}.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	options := &descriptorpb.FileOptions{}
+	if err := format.StructToMessage(result, options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options.GetJavaPackage() != "true" || !options.GetJavaMultipleFiles() || options.Deprecated == nil || options.GetDeprecated() {
+		t.Errorf("Unexpected message %v", options)
+	}
+}
+
+// TestParse_Options tests the attribute prefix, text key and namespace options.
+func TestParse_Options(t *testing.T) {
+	data := []byte(`<config xmlns:c="urn:vendor:common"><server port="80">text<c:addr>0.0.0.0</c:addr></server></config>`)
+	parser := Xml{AttributePrefix: "@", TextKey: "_value", KeepNamespacePrefix: true}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"server": map[string]interface{}{
+			"@port":  "80",
+			"c:addr": "0.0.0.0",
+			"_value": "text",
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_InvalidXML tests error handling for invalid XML.
+func TestParse_InvalidXML(t *testing.T) {
+	tests := map[string]string{
+		"mismatched":        "<a><b></a>",
+		"unclosed":          "<a><b></b>",
+		"multiple roots":    "<a/><b/>",
+		"text root":         "<a>text</a>",
+		"attr conflict":     `<a><b port="1"><port>2</port></b></a>`,
+		"empty document":    "",
+		"text outside root": "<a/>text",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := Xml{}
+			result, err := parser.Parse([]byte(data))
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if result != nil {
+				t.Errorf("Expected nil result, got %v", result)
+			}
+		})
+	}
+}
@@ -1,11 +1,22 @@
 package yaml
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
 	"github.com/soyacen/gonfig/format"
 	"google.golang.org/protobuf/types/known/structpb"
 	"gopkg.in/yaml.v3"
 )
 
+// errorLine matches the line reported by errors of the YAML decoder, e.g. "yaml: line 3: did not find expected key"
+var errorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
 // init registers the Yaml formatter with the global format registry.
 func init() {
 	format.RegisterFormatter("yaml", Yaml{})
@@ -13,7 +24,23 @@ func init() {
 }
 
 // Yaml implements the Formatter interface for environment variables format.
-type Yaml struct{}
+//
+// Timestamps become RFC 3339 strings that map onto google.protobuf.Timestamp fields,
+// e.g. "2001-12-14t21:59:43.10-05:00" becomes "2001-12-14T21:59:43.1-05:00" and the date
+// "2002-12-14" becomes "2002-12-14T00:00:00Z".
+//
+// Non-string map keys are converted to strings the way protojson expects keys of map fields,
+// e.g. the integer key 1 becomes "1" and the boolean key true becomes "true".
+// Anchors, aliases and "<<" merge keys are resolved.
+//
+// Of a multi-document file, the document at index Document is used,
+// or all documents are deep-merged in order if MergeDocuments is set.
+type Yaml struct {
+	// Document is the zero-based index of the document used from a multi-document file
+	Document int
+	// MergeDocuments deep-merges all documents, later documents taking precedence
+	MergeDocuments bool
+}
 
 // Parse converts YAML-formatted byte data into a Protocol Buffer Struct object.
 //
@@ -25,10 +52,237 @@ type Yaml struct{}
 //
 //	*structpb.Struct: A protobuf Struct object representing the parsed data
 //	error: An error if parsing fails (e.g., invalid YAML format or type conversion issues)
-func (Yaml) Parse(data []byte) (*structpb.Struct, error) {
+func (y Yaml) Parse(data []byte) (*structpb.Struct, error) {
+	return y.ParseReader(bytes.NewReader(data))
+}
+
+// ParseReader converts YAML-formatted data read from a stream into a Protocol Buffer Struct object,
+// so that decompressed data is decoded without buffering it.
+//
+// Args:
+//
+//	r (io.Reader): Reader of the YAML-formatted data
+//
+// Returns:
+//
+//	*structpb.Struct: A protobuf Struct object representing the parsed data
+//	error: An error if reading or parsing fails
+func (y Yaml) ParseReader(r io.Reader) (*structpb.Struct, error) {
+	var documents []map[string]any
+	decoder := yaml.NewDecoder(r)
+	for i := 0; ; i++ {
+		var v any
+		if err := decoder.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, parseError(err)
+		}
+		document, err := toMap(convert(v))
+		if err != nil {
+			return nil, &format.ParseError{Err: fmt.Errorf("yaml: document %d: %w", i, err)}
+		}
+		documents = append(documents, document)
+	}
+
 	v := make(map[string]any)
-	if err := yaml.Unmarshal(data, &v); err != nil {
-		return nil, err
+	switch {
+	case y.MergeDocuments:
+		for _, document := range documents {
+			v = merge(v, document)
+		}
+	case y.Document < 0:
+		return nil, fmt.Errorf("yaml: invalid document index %d", y.Document)
+	case y.Document < len(documents):
+		if documents[y.Document] != nil {
+			v = documents[y.Document]
+		}
+	case y.Document > 0:
+		return nil, fmt.Errorf("yaml: document %d not found, the file has %d documents", y.Document, len(documents))
 	}
 	return structpb.NewStruct(v)
 }
+
+// Locate returns the position of the key of the field at path, or of the list item, in the YAML data.
+// Fields are looked up in the document used by Parse; with MergeDocuments, in the last document having them.
+// Aliases and "<<" merge keys are followed.
+//
+// Args:
+//
+//	data ([]byte): The YAML-formatted byte slice
+//	path (string): Field path, e.g. "redis.port" or "routes[0].path"
+//
+// Returns:
+//
+//	format.Position: Position of the field
+//	bool: Whether the field was found
+func (y Yaml) Locate(data []byte, path string) (format.Position, bool) {
+	segments := format.SplitPath(path)
+	if segments == nil {
+		return format.Position{}, false
+	}
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			break
+		}
+		documents = append(documents, &document)
+	}
+	if !y.MergeDocuments {
+		if y.Document < 0 || y.Document >= len(documents) {
+			return format.Position{}, false
+		}
+		documents = documents[y.Document : y.Document+1]
+	}
+	for i := len(documents) - 1; i >= 0; i-- {
+		if node := locate(documents[i], segments); node != nil {
+			return format.Position{Line: node.Line, Column: node.Column}, true
+		}
+	}
+	return format.Position{}, false
+}
+
+// locate returns the key node of the field, or the item node, at the path segments below a node
+func locate(node *yaml.Node, segments []any) *yaml.Node {
+	if len(segments) == 0 {
+		return node
+	}
+	node = resolve(node)
+	switch segment := segments[0].(type) {
+	case string:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		// Explicit keys take precedence over merged keys
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Value == segment && key.Tag != "!!merge" {
+				if len(segments) == 1 {
+					return key
+				}
+				return locate(node.Content[i+1], segments[1:])
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag != "!!merge" {
+				continue
+			}
+			merged := resolve(node.Content[i+1])
+			sources := []*yaml.Node{merged}
+			if merged.Kind == yaml.SequenceNode {
+				sources = merged.Content
+			}
+			for _, source := range sources {
+				if found := locate(source, segments); found != nil {
+					return found
+				}
+			}
+		}
+	case int:
+		if node.Kind == yaml.SequenceNode && segment < len(node.Content) {
+			return locate(node.Content[segment], segments[1:])
+		}
+	}
+	return nil
+}
+
+// resolve returns the content of document nodes and the target of aliases
+func resolve(node *yaml.Node) *yaml.Node {
+	for {
+		switch {
+		case node.Kind == yaml.DocumentNode && len(node.Content) > 0:
+			node = node.Content[0]
+		case node.Kind == yaml.AliasNode && node.Alias != nil:
+			node = node.Alias
+		default:
+			return node
+		}
+	}
+}
+
+// parseError converts an error of the YAML decoder into a format.ParseError with the line it reports
+func parseError(err error) error {
+	match := errorLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return &format.ParseError{Err: err}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &format.ParseError{Position: format.Position{Line: line}, Err: errors.New("yaml: " + match[2])}
+}
+
+// toMap returns the top-level mapping of a document, nil for an empty document
+func toMap(v any) (map[string]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("top-level value must be a mapping, got %T", v)
+	}
+}
+
+// convert replaces timestamps with RFC 3339 strings and non-string map keys with strings, recursively
+func convert(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = convert(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[keyString(key)] = convert(value)
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = convert(value)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// keyString converts a map key to the string protojson expects for keys of map fields
+func keyString(key any) string {
+	switch key := key.(type) {
+	case string:
+		return key
+	case int:
+		return strconv.Itoa(key)
+	case int64:
+		return strconv.FormatInt(key, 10)
+	case uint64:
+		return strconv.FormatUint(key, 10)
+	case bool:
+		return strconv.FormatBool(key)
+	case float64:
+		return strconv.FormatFloat(key, 'g', -1, 64)
+	case time.Time:
+		return key.Format(time.RFC3339Nano)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(key)
+	}
+}
+
+// merge deep-merges src into dst, nested mappings are merged and other values are replaced
+func merge(dst, src map[string]any) map[string]any {
+	for key, value := range src {
+		srcMap, srcOk := value.(map[string]any)
+		dstMap, dstOk := dst[key].(map[string]any)
+		if srcOk && dstOk {
+			dst[key] = merge(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+	return dst
+}
@@ -1,8 +1,16 @@
 package yaml
 
 import (
+	"errors"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/soyacen/gonfig/format"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // TestParse_Success tests successful parsing of valid YAML data.
@@ -42,3 +50,204 @@ func TestParse_InvalidYAML(t *testing.T) {
 		t.Errorf("Expected nil result, got %v", result)
 	}
 }
+
+// TestParse_Timestamp tests conversion of YAML timestamps into RFC 3339 strings.
+func TestParse_Timestamp(t *testing.T) {
+	data := []byte(`
+canonical: 2001-12-15T02:59:43.1Z
+iso8601: 2001-12-14t21:59:43.10-05:00
+date: 2002-12-14
+quoted: "2002-12-14"
+nested:
+  list: [2001-12-15T02:59:43Z]
+`)
+	parser := Yaml{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"canonical": "2001-12-15T02:59:43.1Z",
+		"iso8601":   "2001-12-14T21:59:43.1-05:00",
+		"date":      "2002-12-14T00:00:00Z",
+		"quoted":    "2002-12-14",
+		"nested":    map[string]interface{}{"list": []interface{}{"2001-12-15T02:59:43Z"}},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+
+	// Timestamps map onto google.protobuf.Timestamp
+	for _, key := range []string{"canonical", "iso8601", "date"} {
+		value := result.GetFields()[key].GetStringValue()
+		if err := protojson.Unmarshal([]byte(strconv.Quote(value)), &timestamppb.Timestamp{}); err != nil {
+			t.Errorf("Expected %s to be a valid Timestamp, got %v", key, err)
+		}
+	}
+}
+
+// TestParse_NonStringKeys tests conversion of non-string map keys into protojson map keys.
+func TestParse_NonStringKeys(t *testing.T) {
+	data := []byte(`
+ports:
+  80: http
+  443: https
+flags:
+  true: enabled
+  false: disabled
+ratios:
+  0.5: half
+`)
+	parser := Yaml{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"ports":  map[string]interface{}{"80": "http", "443": "https"},
+		"flags":  map[string]interface{}{"true": "enabled", "false": "disabled"},
+		"ratios": map[string]interface{}{"0.5": "half"},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_Anchors tests resolution of anchors, aliases and merge keys.
+func TestParse_Anchors(t *testing.T) {
+	data := []byte(`
+defaults: &defaults
+  timeout: 5s
+  retries: 3
+hosts: &hosts [a, b]
+production:
+  <<: *defaults
+  retries: 5
+  hosts: *hosts
+`)
+	parser := Yaml{}
+	result, err := parser.Parse(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedMap := map[string]interface{}{
+		"defaults": map[string]interface{}{"timeout": "5s", "retries": float64(3)},
+		"hosts":    []interface{}{"a", "b"},
+		"production": map[string]interface{}{
+			"timeout": "5s",
+			"retries": float64(5),
+			"hosts":   []interface{}{"a", "b"},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedMap, result.AsMap()) {
+		t.Errorf("Expected map %v, got %v", expectedMap, result.AsMap())
+	}
+}
+
+// TestParse_MultiDocument tests selecting and merging documents of a multi-document file.
+func TestParse_MultiDocument(t *testing.T) {
+	data := []byte(`
+server:
+  addr: 0.0.0.0
+  port: 8080
+---
+---
+server:
+  port: 9090
+name: second
+`)
+	tests := []struct {
+		name     string
+		parser   Yaml
+		expected map[string]interface{}
+	}{
+		{"First", Yaml{}, map[string]interface{}{
+			"server": map[string]interface{}{"addr": "0.0.0.0", "port": float64(8080)},
+		}},
+		{"Empty", Yaml{Document: 1}, map[string]interface{}{}},
+		{"Index", Yaml{Document: 2}, map[string]interface{}{
+			"server": map[string]interface{}{"port": float64(9090)},
+			"name":   "second",
+		}},
+		{"Merge", Yaml{MergeDocuments: true}, map[string]interface{}{
+			"server": map[string]interface{}{"addr": "0.0.0.0", "port": float64(9090)},
+			"name":   "second",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.parser.Parse(data)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(tt.expected, result.AsMap()) {
+				t.Errorf("Expected map %v, got %v", tt.expected, result.AsMap())
+			}
+		})
+	}
+
+	if _, err := (Yaml{Document: 3}).Parse(data); err == nil {
+		t.Error("Expected error for missing document")
+	}
+	if _, err := (Yaml{}).Parse([]byte("- a\n- b")); err == nil {
+		t.Error("Expected error for non-mapping document")
+	}
+}
+
+// TestParse_ErrorPosition tests that syntax errors report their line.
+func TestParse_ErrorPosition(t *testing.T) {
+	data := []byte("name: Alice\nredis:\n  addr: localhost\n  port: : 6379\n")
+	_, err := Yaml{}.Parse(data)
+	var parseErr *format.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *format.ParseError, got %v", err)
+	}
+	if parseErr.Line != 4 || strings.Contains(parseErr.Err.Error(), "line") {
+		t.Errorf("Expected an error at line 4 without the line in its message, got %v", err)
+	}
+}
+
+// TestLocate tests finding the positions of fields, list items, aliases and merge keys.
+func TestLocate(t *testing.T) {
+	data := []byte(`defaults: &defaults
+  timeout: 5s
+redis:
+  <<: *defaults
+  addr: localhost
+  port: abc
+routes:
+  - path: /api
+  - path: /admin
+    upstream: admin
+---
+name: second
+`)
+	tests := []struct {
+		yaml     Yaml
+		path     string
+		expected format.Position
+		found    bool
+	}{
+		{Yaml{}, "redis.port", format.Position{Line: 6, Column: 3}, true},
+		{Yaml{}, "redis.timeout", format.Position{Line: 2, Column: 3}, true},
+		{Yaml{}, "routes[1]", format.Position{Line: 9, Column: 5}, true},
+		{Yaml{}, "routes[1].upstream", format.Position{Line: 10, Column: 5}, true},
+		{Yaml{}, "routes[2]", format.Position{}, false},
+		{Yaml{}, "name", format.Position{}, false},
+		{Yaml{Document: 1}, "name", format.Position{Line: 12, Column: 1}, true},
+		{Yaml{MergeDocuments: true}, "name", format.Position{Line: 12, Column: 1}, true},
+		{Yaml{MergeDocuments: true}, "redis.addr", format.Position{Line: 5, Column: 3}, true},
+	}
+	for _, tt := range tests {
+		position, found := tt.yaml.Locate(data, tt.path)
+		if found != tt.found || position != tt.expected {
+			t.Errorf("Locate(%q) = %v, %v; want %v, %v", tt.path, position, found, tt.expected, tt.found)
+		}
+	}
+}
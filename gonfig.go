@@ -3,26 +3,49 @@ package gonfig
 import (
 	"context"
 
+	"github.com/soyacen/gonfig/format"
 	"github.com/soyacen/gonfig/resource"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
-func Load[Config proto.Message](ctx context.Context, resource resource.Resource) (Config, error) {
+// ParseError reports configuration data that cannot be parsed, with its source, line, column and field path when known.
+// Load, Watch and the resources return it wrapped or as is, use errors.As to inspect it.
+type ParseError = format.ParseError
+
+// ConvertError reports parsed configuration data that does not match the message,
+// e.g. a string for an integer field. The field path is mapped back to its source, line and column
+// when the resource and format support it, e.g. for YAML and JSON files.
+type ConvertError = format.ConvertError
+
+func Load[Config proto.Message](ctx context.Context, rsc resource.Resource) (Config, error) {
+	if messageResource, ok := rsc.(resource.MessageResource); ok {
+		config := newConfig[Config]()
+		if err := messageResource.LoadMessage(ctx, config); err != nil {
+			return config, err
+		}
+		return config, nil
+	}
 	var config Config
-	value, err := resource.Load(ctx)
+	sensitive := &format.Sensitive{}
+	value, err := rsc.Load(format.ContextWithSensitive(ctx, sensitive))
 	if err != nil {
 		return config, err
 	}
-	return convert[Config](value)
+	return convert[Config](value, sensitive)
 }
 
-func Watch[Config proto.Message](ctx context.Context, resource resource.Resource, notifyFunc func(conf Config), errFunc resource.ErrFunc) (resource.StopFunc, error) {
-	stopFunc, err := resource.Watch(
-		ctx,
+func Watch[Config proto.Message](ctx context.Context, rsc resource.Resource, notifyFunc func(conf Config), errFunc resource.ErrFunc) (resource.StopFunc, error) {
+	// Formats decoding data directly into messages need the message type, Load may not have been called
+	if messageResource, ok := rsc.(resource.MessageResource); ok {
+		messageResource.RegisterMessage(newConfig[Config]())
+	}
+	// Values marked as sensitive by transformers while watching are redacted from conversion errors
+	sensitive := &format.Sensitive{}
+	stopFunc, err := rsc.Watch(
+		format.ContextWithSensitive(ctx, sensitive),
 		func(value *structpb.Struct) {
-			conf, err := convert[Config](value)
+			conf, err := decode[Config](rsc, value, sensitive)
 			if err != nil {
 				panic(err)
 			}
@@ -36,15 +59,31 @@ func Watch[Config proto.Message](ctx context.Context, resource resource.Resource
 	return stopFunc, nil
 }
 
-func convert[Config proto.Message](value *structpb.Struct) (Config, error) {
-	var config Config
-	data, err := value.MarshalJSON()
-	if err != nil {
-		return config, err
+// decode decodes a notified value, from the resource's data it was parsed from if the resource supports it
+func decode[Config proto.Message](rsc resource.Resource, value *structpb.Struct, sensitive *format.Sensitive) (Config, error) {
+	decoder, ok := rsc.(resource.ValueDecoder)
+	if !ok {
+		return convert[Config](value, sensitive)
 	}
-	config = config.ProtoReflect().Type().New().Interface().(Config)
-	if err := protojson.Unmarshal(data, config); err != nil {
-		return config, err
+	config := newConfig[Config]()
+	if err := decoder.DecodeValue(value, config); err != nil {
+		return config, sensitive.Redact(err)
+	}
+	return config, nil
+}
+
+// newConfig creates an empty message of type Config
+func newConfig[Config proto.Message]() Config {
+	var config Config
+	return config.ProtoReflect().Type().New().Interface().(Config)
+}
+
+// convert converts a value into a message, errors are a *ConvertError with the path of the offending field
+// and the sensitive values redacted
+func convert[Config proto.Message](value *structpb.Struct, sensitive *format.Sensitive) (Config, error) {
+	config := newConfig[Config]()
+	if err := format.StructToMessage(value, config); err != nil {
+		return config, sensitive.Redact(err)
 	}
 	return config, nil
 }
@@ -1,18 +1,46 @@
 package gonfig
 
 import (
+	// Protobuf binary wire format support
+	// Automatically registers binpb format decoder when imported
+	_ "github.com/soyacen/gonfig/format/binpb"
+
 	// Environment variable format support
 	// Automatically registers env format decoder when imported
 	_ "github.com/soyacen/gonfig/format/env"
 
+	// HCL format support
+	// Automatically registers hcl format decoder when imported
+	_ "github.com/soyacen/gonfig/format/hcl"
+
+	// INI format support
+	// Automatically registers ini and cfg format decoders when imported
+	_ "github.com/soyacen/gonfig/format/ini"
+
 	// JSON format support
 	// Automatically registers json format decoder when imported
 	_ "github.com/soyacen/gonfig/format/json"
 
+	// JSON5 and JSONC format support
+	// Automatically registers json5 and jsonc format decoders when imported
+	_ "github.com/soyacen/gonfig/format/json5"
+
+	// Java properties format support
+	// Automatically registers properties format decoder when imported
+	_ "github.com/soyacen/gonfig/format/properties"
+
+	// Protobuf text format support
+	// Automatically registers txtpb and textproto format decoders when imported
+	_ "github.com/soyacen/gonfig/format/prototext"
+
 	// TOML format support
 	// Automatically registers toml format decoder when imported
 	_ "github.com/soyacen/gonfig/format/toml"
 
+	// XML format support
+	// Automatically registers xml format decoder when imported
+	_ "github.com/soyacen/gonfig/format/xml"
+
 	// YAML format support
 	// Automatically registers yaml format decoder when imported
 	_ "github.com/soyacen/gonfig/format/yaml"
@@ -0,0 +1,287 @@
+// Package chain provides a fallback implementation of the configuration resource interface
+// that wraps an ordered list of resources and follows the highest-priority healthy one
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ resource.Resource = (*Resource)(nil)
+
+// Resource represents an ordered chain of configuration resources.
+// The first resource has the highest priority, the last one is the resource of last resort.
+type Resource struct {
+	// resources is the ordered list of wrapped resources
+	resources []resource.Resource
+	// interval defines how often unhealthy resources are probed for recovery
+	interval time.Duration
+	// mutex protects states and active
+	mutex sync.Mutex
+	// states holds the latest known state of every wrapped resource
+	states []*state
+	// active is the index of the resource currently in use, -1 if none
+	active int
+}
+
+// state is the latest known state of a single wrapped resource
+type state struct {
+	// value is the latest configuration data delivered by the resource
+	value *structpb.Struct
+	// err is the latest error reported by the resource, nil if healthy
+	err error
+}
+
+// healthy reports whether the resource can currently be used
+func (s *state) healthy() bool {
+	return s.err == nil && s.value != nil
+}
+
+// Status describes which resource of the chain is currently active
+type Status struct {
+	// Active is the index of the active resource, -1 if no resource is healthy
+	Active int
+	// Sources holds the status of every resource in priority order
+	Sources []SourceStatus
+}
+
+// SourceStatus describes the health of a single resource of the chain
+type SourceStatus struct {
+	// Index is the position of the resource in the chain
+	Index int
+	// Healthy reports whether the resource delivered data and has not failed since
+	Healthy bool
+	// Err is the latest error reported by the resource
+	Err error
+}
+
+// Load retrieves the configuration from the first resource that succeeds
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//
+// Returns:
+//   - *structpb.Struct: Configuration data of the first healthy resource
+//   - error: Joined errors of all resources if every one of them failed
+func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
+	var errs []error
+	for i, rsc := range r.resources {
+		value, err := rsc.Load(ctx)
+		r.update(i, value, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gonfig: chain resource %d: %w", i, err))
+			continue
+		}
+		r.mutex.Lock()
+		r.active = i
+		r.mutex.Unlock()
+		return value, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Watch monitors every resource of the chain and notifies subscribers with the data
+// of the highest-priority healthy resource. It switches over when the active resource
+// fails and switches back when a higher-priority resource recovers.
+// Parameters:
+//   - ctx: Context for cancellation
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+//
+// Returns:
+//   - resource.StopFunc: Function to stop watching
+//   - error: Any immediate error during setup
+func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
+	// Validate notify function
+	if notifyFunc == nil {
+		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+	}
+
+	// Set default error handler if none provided
+	if errFunc == nil {
+		errFunc = func(err error) {
+			slog.Error("gonfig: failed to watch chain", slog.String("error", err.Error()))
+		}
+	}
+
+	// Check if context is already cancelled
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Watch every resource, a resource that cannot be watched is marked unhealthy
+	stops := make([]resource.StopFunc, 0, len(r.resources))
+	var errs []error
+	for i, rsc := range r.resources {
+		stop, err := rsc.Watch(
+			ctx,
+			func(value *structpb.Struct) {
+				r.update(i, value, nil)
+				r.elect(i, notifyFunc)
+			},
+			func(err error) {
+				// Cancellation is reported once by the chain itself
+				if ctx.Err() != nil {
+					return
+				}
+				r.update(i, nil, err)
+				r.elect(i, notifyFunc)
+				errFunc(fmt.Errorf("gonfig: chain resource %d: %w", i, err))
+			},
+		)
+		if err != nil {
+			r.update(i, nil, err)
+			errs = append(errs, fmt.Errorf("gonfig: chain resource %d: %w", i, err))
+			continue
+		}
+		stops = append(stops, stop)
+	}
+	if len(stops) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Create stop function with sync.Once to ensure it's only called once
+	stopC := make(chan struct{})
+	var onceStop sync.Once
+	var stopErr error
+	stop := func(ctx context.Context) error {
+		onceStop.Do(func() {
+			close(stopC)
+			for _, stop := range stops {
+				stopErr = errors.Join(stopErr, stop(ctx))
+			}
+		})
+		return stopErr
+	}
+
+	// Start probing unhealthy resources in a separate goroutine
+	go func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Context cancelled, exit goroutine
+				errFunc(ctx.Err())
+				return
+
+			case <-stopC:
+				// Stop signal received, exit goroutine
+				return
+
+			case <-timer.C:
+				// Reload every resource that has no usable data
+				for i, rsc := range r.resources {
+					if r.isHealthy(i) {
+						continue
+					}
+					value, err := rsc.Load(ctx)
+					if ctx.Err() != nil {
+						break
+					}
+					r.update(i, value, err)
+					r.elect(i, notifyFunc)
+				}
+				timer.Reset(r.interval)
+			}
+		}
+	}()
+
+	return stop, nil
+}
+
+// Status returns a snapshot of the health of the chain
+func (r *Resource) Status() Status {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	status := Status{Active: r.active, Sources: make([]SourceStatus, 0, len(r.states))}
+	for i, s := range r.states {
+		status.Sources = append(status.Sources, SourceStatus{Index: i, Healthy: s.healthy(), Err: s.err})
+	}
+	return status
+}
+
+// update records the latest value or error of the resource at index i
+func (r *Resource) update(i int, value *structpb.Struct, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s := r.states[i]
+	s.err = err
+	if err == nil {
+		s.value = value
+	}
+}
+
+// isHealthy reports whether the resource at index i is healthy
+func (r *Resource) isHealthy(i int) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.states[i].healthy()
+}
+
+// elect selects the highest-priority healthy resource after the resource at index i changed.
+// Subscribers are notified when the active resource changes or when the active resource
+// itself delivered new data.
+func (r *Resource) elect(i int, notifyFunc resource.NotifyFunc) {
+	r.mutex.Lock()
+	active := -1
+	for j, s := range r.states {
+		if s.healthy() {
+			active = j
+			break
+		}
+	}
+	changed := active != r.active
+	r.active = active
+	var value *structpb.Struct
+	if active >= 0 && (changed || active == i) {
+		value = r.states[active].value
+	}
+	r.mutex.Unlock()
+	if value != nil {
+		notifyFunc(value)
+	}
+}
+
+// New creates a new chain configuration resource
+// Parameters:
+//   - interval: How often unhealthy resources are probed for recovery (default 5 seconds)
+//   - resources: Resources in priority order, the first one has the highest priority
+//
+// Returns:
+//   - *Resource: New chain resource instance
+//   - error: Any error during initialization
+func New(interval time.Duration, resources ...resource.Resource) (*Resource, error) {
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("gonfig: chain has no resources")
+	}
+	for i, rsc := range resources {
+		if rsc == nil {
+			return nil, fmt.Errorf("gonfig: chain resource %d is nil", i)
+		}
+	}
+
+	// Set default interval if not provided or invalid
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	states := make([]*state, 0, len(resources))
+	for range resources {
+		states = append(states, &state{})
+	}
+
+	// Return new resource instance
+	return &Resource{
+		resources: resources,
+		interval:  interval,
+		states:    states,
+		active:    -1,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeResource is an in-memory resource whose data and failures are driven by the test
+type fakeResource struct {
+	mutex      sync.Mutex
+	value      *structpb.Struct
+	err        error
+	notifyFunc resource.NotifyFunc
+	errFunc    resource.ErrFunc
+}
+
+func newFakeResource(name string) *fakeResource {
+	value, _ := structpb.NewStruct(map[string]any{"name": name})
+	return &fakeResource{value: value}
+}
+
+func (r *fakeResource) Load(ctx context.Context) (*structpb.Struct, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.value, nil
+}
+
+func (r *fakeResource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.notifyFunc = notifyFunc
+	r.errFunc = errFunc
+	return func(context.Context) error { return nil }, nil
+}
+
+func (r *fakeResource) fail(err error) {
+	r.mutex.Lock()
+	r.err = err
+	errFunc := r.errFunc
+	r.mutex.Unlock()
+	errFunc(err)
+}
+
+func (r *fakeResource) recover() {
+	r.mutex.Lock()
+	r.err = nil
+	notifyFunc := r.notifyFunc
+	value := r.value
+	r.mutex.Unlock()
+	notifyFunc(value)
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(time.Second); err == nil {
+		t.Error("expected error for empty chain")
+	}
+	if _, err := New(time.Second, newFakeResource("a"), nil); err == nil {
+		t.Error("expected error for nil resource")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	primary := newFakeResource("primary")
+	primary.err = errors.New("primary down")
+	secondary := newFakeResource("secondary")
+
+	r, err := New(time.Second, primary, secondary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := r.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := value.GetFields()["name"].GetStringValue(); name != "secondary" {
+		t.Errorf("expected value from secondary; got %q", name)
+	}
+	status := r.Status()
+	if status.Active != 1 {
+		t.Errorf("expected active 1; got %d", status.Active)
+	}
+	if status.Sources[0].Healthy || status.Sources[0].Err == nil {
+		t.Errorf("expected primary to be unhealthy; got %+v", status.Sources[0])
+	}
+
+	secondary.err = errors.New("secondary down")
+	if _, err := r.Load(context.Background()); err == nil {
+		t.Error("expected error when every resource fails")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	primary := newFakeResource("primary")
+	secondary := newFakeResource("secondary")
+
+	r, err := New(time.Hour, primary, secondary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan string, 10)
+	notifyFunc := func(value *structpb.Struct) {
+		c <- value.GetFields()["name"].GetStringValue()
+	}
+	stop, err := r.Watch(ctx, notifyFunc, func(error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// wait until the initial probe has loaded the secondary resource
+	deadline := time.Now().Add(time.Second)
+	for !r.Status().Sources[1].Healthy && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	primary.fail(errors.New("primary down"))
+	if name := <-c; name != "secondary" {
+		t.Errorf("expected switch over to secondary; got %q", name)
+	}
+	if active := r.Status().Active; active != 1 {
+		t.Errorf("expected active 1; got %d", active)
+	}
+
+	primary.recover()
+	if name := <-c; name != "primary" {
+		t.Errorf("expected switch back to primary; got %q", name)
+	}
+	if active := r.Status().Active; active != 0 {
+		t.Errorf("expected active 0; got %d", active)
+	}
+}
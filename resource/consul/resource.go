@@ -6,22 +6,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
 	"sync"
-	"sync/atomic"
 
-	"github.com/soyacen/gonfig/format"
 	"github.com/soyacen/gonfig/resource"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/api/watch"
 	"github.com/hashicorp/go-hclog"
 
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
-var _ resource.Resource = (*Resource)(nil)
+var (
+	_ resource.MessageResource = (*Resource)(nil)
+	_ resource.ValueDecoder    = (*Resource)(nil)
+)
 
 // Resource represents a configuration resource stored in Consul KV store
 type Resource struct {
@@ -29,10 +31,10 @@ type Resource struct {
 	client *api.Client
 	// key is the path to the configuration in the Consul KV store
 	key string
-	// formatter is used for parsing config data into structured format
-	formatter format.Formatter
-	// pre is atomic storage for previous configuration data to detect changes
-	pre atomic.Value
+	// keys are the paths of the configuration followed by its profile-specific variants
+	keys []string
+	// core parses config data and suppresses unchanged payloads
+	core *resource.Core
 }
 
 // Load retrieves and parses the configuration from Consul KV store
@@ -44,38 +46,72 @@ type Resource struct {
 //   - *structpb.Struct: Parsed configuration data
 //   - error: Any error that occurred during loading or parsing
 func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
-	data, err := r.load(ctx)
-	if err != nil {
-		return nil, err
-	}
-	parsed, err := r.formatter.Parse(data)
-	if err != nil {
-		return nil, err
-	}
-	r.pre.Store(data)
-	return parsed, nil
+	return r.core.Load(ctx)
 }
 
-// load is an internal helper function to fetch raw data from Consul KV store
+// LoadMessage retrieves the configuration and decodes it into a message
+// Formatters implementing format.MessageFormatter decode the data directly into the message
 // Parameters:
 //   - ctx: Context for cancellation and timeouts
+//   - message: Message the configuration data is decoded into
 //
 // Returns:
-//   - []byte: Raw configuration data from Consul
-//   - error: Any error that occurred while fetching from Consul
-func (r *Resource) load(ctx context.Context) ([]byte, error) {
-	pair, _, err := r.client.KV().Get(r.key, new(api.QueryOptions).WithContext(ctx))
-	if err != nil {
-		return nil, err
-	}
-	if pair == nil {
-		return nil, fmt.Errorf("gonfig: consul key %q not found", r.key)
+//   - error: Any error that occurred during loading or decoding
+func (r *Resource) LoadMessage(ctx context.Context, message proto.Message) error {
+	return r.core.LoadMessage(ctx, message)
+}
+
+// DecodeMessage decodes the configuration of the latest load or change into a message
+// Parameters:
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (r *Resource) DecodeMessage(message proto.Message) error {
+	return r.core.DecodeMessage(message)
+}
+
+// DecodeValue decodes a configuration returned by Load or notified by Watch into a message,
+// from the data it was parsed from even if it has changed since
+// Parameters:
+//   - value: Configuration data returned by Load or notified by Watch
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (r *Resource) DecodeValue(value *structpb.Struct, message proto.Message) error {
+	return r.core.DecodeValue(value, message)
+}
+
+// RegisterMessage registers the type of the message the configuration is decoded into,
+// so that formats decoding data directly into messages work with Watch without a previous LoadMessage
+// Parameters:
+//   - message: Message of the type the configuration data is decoded into
+func (r *Resource) RegisterMessage(message proto.Message) {
+	r.core.RegisterMessage(message)
+}
+
+// load is an internal helper function returning a fetch function for raw data from Consul KV store
+// Parameters:
+//   - key: Path to the configuration in the Consul KV store
+//
+// Returns:
+//   - resource.FetchFunc: Function fetching the raw configuration data from Consul
+func (r *Resource) load(key string) resource.FetchFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		pair, _, err := r.client.KV().Get(key, new(api.QueryOptions).WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if pair == nil {
+			return nil, fmt.Errorf("gonfig: consul key %q not found: %w", key, resource.ErrNotExist)
+		}
+		return pair.Value, nil
 	}
-	return pair.Value, nil
 }
 
 // Watch sets up a watcher for configuration changes in Consul using Consul's watch mechanism
-// It creates a watch plan that monitors changes to the specific key and notifies subscribers
+// It creates watch plans that monitor changes to the key and its profile-specific variants and notifies subscribers
 // Parameters:
 //   - ctx: Context for cancellation
 //   - notifyFunc: Callback function for configuration updates
@@ -85,27 +121,75 @@ func (r *Resource) load(ctx context.Context) ([]byte, error) {
 //   - resource.StopFunc: Function to stop watching
 //   - error: Any immediate error during setup
 func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
-	// Validate notify function
-	if notifyFunc == nil {
-		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+	// Validate arguments and set default error handler
+	errFunc, err := r.core.PrepareWatch(ctx, notifyFunc, errFunc)
+	if err != nil {
+		return nil, err
 	}
 
-	// Set default error handler if none provided
-	if errFunc == nil {
-		errFunc = func(err error) {
-			slog.Error("gonfig: failed to watch consul", slog.String("error", err.Error()))
+	// Watch the included keys, the set of included keys may change with every change
+	includes := &includeWatcher{r: r, ctx: ctx, notifyFunc: notifyFunc, errFunc: errFunc, plans: make(map[string]*watch.Plan)}
+
+	// Create a watch plan for every key
+	plans := make([]*watch.Plan, 0, len(r.keys))
+	for i, key := range r.keys {
+		plan, err := r.plan(i, key, notifyFunc, errFunc, includes.sync)
+		if err != nil {
+			return nil, err
 		}
+		plans = append(plans, plan)
 	}
 
-	// Check if context is already cancelled
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// Start watching in separate goroutines
+	for _, plan := range plans {
+		go r.run(plan, errFunc)
 	}
+	includes.sync()
 
+	// Create stop function
+	stop, stopC := resource.NewStopFunc()
+
+	// Start a goroutine to handle context cancellation
+	go func() {
+		// Ensure plans are stopped when goroutine exits
+		defer func() {
+			for _, plan := range plans {
+				plan.Stop()
+			}
+			includes.stop()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				// Context cancelled, exit goroutine
+				errFunc(ctx.Err())
+				return
+			case <-stopC:
+				// Stop signal received, exit goroutine
+				return
+			}
+		}
+	}()
+
+	return stop, nil
+}
+
+// plan creates a watch plan monitoring a single key
+// Parameters:
+//   - i: Index of the key, 0 is the configuration and profile-specific variants follow
+//   - key: Path to the configuration in the Consul KV store
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+//   - changed: Callback function called after the data of the key has been handled
+//
+// Returns:
+//   - *watch.Plan: Watch plan for the key
+//   - error: Any error creating the plan
+func (r *Resource) plan(i int, key string, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc, changed func()) (*watch.Plan, error) {
 	// Prepare watch parameters for key monitoring
 	params := map[string]any{
 		"type": "key",
-		"key":  r.key,
+		"key":  key,
 	}
 
 	// Create watch plan
@@ -118,6 +202,12 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 	plan.Handler = func(idx uint64, raw interface{}) {
 		// Validate the received data
 		if raw == nil {
+			// A missing profile-specific key is not an error, its data is removed
+			if i > 0 {
+				r.core.NotifyLayer(i, nil, notifyFunc, errFunc)
+				changed()
+				return
+			}
 			errFunc(fmt.Errorf("gonfig: consul watch returned unexpected type %T", raw))
 			return
 		}
@@ -128,69 +218,99 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 			return
 		}
 
-		// Get the data value
-		data := pair.Value
+		// Parse and notify subscribers of the change
+		r.core.NotifyLayer(i, pair.Value, notifyFunc, errFunc)
+		changed()
+	}
+	return plan, nil
+}
 
-		// Compare with previous data to avoid unnecessary notifications
-		preData := r.pre.Load()
-		if preData != nil && bytes.Equal(preData.([]byte), data) {
-			return
-		}
+// run runs a watch plan until it is stopped
+// Parameters:
+//   - plan: Watch plan to run
+//   - errFunc: Callback function for error reporting
+func (r *Resource) run(plan *watch.Plan, errFunc resource.ErrFunc) {
+	// Create custom logger that forwards errors to errFunc
+	logger := &consulLogger{
+		Logger:  hclog.NewNullLogger(),
+		errFunc: errFunc,
+	}
 
-		// Parse new configuration data
-		newValue, err := r.formatter.Parse(data)
-		if err != nil {
-			errFunc(err)
-			return
-		}
+	// Run the watch plan with the Consul client
+	if err := plan.RunWithClientAndHclog(r.client, logger); err != nil {
+		errFunc(err)
+	}
+}
 
-		// Notify subscribers of the change
-		notifyFunc(newValue)
+// includeWatcher watches the keys included by the configuration and the key prefixes of include patterns,
+// and parses the configuration again when they change
+type includeWatcher struct {
+	r          *Resource
+	ctx        context.Context
+	notifyFunc resource.NotifyFunc
+	errFunc    resource.ErrFunc
+	// mutex protects plans and stopped
+	mutex sync.Mutex
+	// plans are the running watch plans by watched key, prefixes end with "*"
+	plans   map[string]*watch.Plan
+	stopped bool
+}
 
-		// Store new data for future comparisons
-		r.pre.Store(data)
+// sync starts watching newly included keys and stops watching keys no longer included
+func (w *includeWatcher) sync() {
+	includes, patterns := w.r.core.Includes()
+	params := make(map[string]map[string]any, len(includes)+len(patterns))
+	for _, include := range includes {
+		params[include] = map[string]any{"type": "key", "key": include}
+	}
+	for _, pattern := range patterns {
+		prefix := globPrefix(pattern)
+		params[prefix+"*"] = map[string]any{"type": "keyprefix", "prefix": prefix}
 	}
 
-	// Start watching in a separate goroutine
-	go func() {
-		// Create custom logger that forwards errors to errFunc
-		logger := &consulLogger{
-			Logger:  hclog.NewNullLogger(),
-			errFunc: errFunc,
-		}
-
-		// Run the watch plan with the Consul client
-		if err := plan.RunWithClientAndHclog(r.client, logger); err != nil {
-			errFunc(err)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stopped {
+		return
+	}
+	for name, plan := range w.plans {
+		if _, ok := params[name]; !ok {
+			plan.Stop()
+			delete(w.plans, name)
 		}
-	}()
-
-	// Create stop function with sync.Once to ensure it's only called once
-	stopC := make(chan struct{})
-	var onceStop sync.Once
-	stop := func(ctx context.Context) error {
-		onceStop.Do(func() { close(stopC) })
-		return nil
 	}
-
-	// Start a goroutine to handle context cancellation
-	go func() {
-		// Ensure plan is stop when goroutine exits
-		defer plan.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				// Context cancelled, exit goroutine
-				errFunc(ctx.Err())
-				return
-			case <-stopC:
-				// Stop signal received, exit goroutine
+	for name, param := range params {
+		if _, ok := w.plans[name]; ok {
+			continue
+		}
+		plan, err := watch.Parse(param)
+		if err != nil {
+			w.errFunc(err)
+			continue
+		}
+		// The first call reports the current data, which has already been parsed
+		first := true
+		plan.Handler = func(uint64, interface{}) {
+			if first {
+				first = false
 				return
 			}
+			w.r.core.Refresh(w.ctx, w.notifyFunc, w.errFunc)
+			w.sync()
 		}
-	}()
+		w.plans[name] = plan
+		go w.r.run(plan, w.errFunc)
+	}
+}
 
-	return stop, nil
+// stop stops watching the included keys
+func (w *includeWatcher) stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.stopped = true
+	for _, plan := range w.plans {
+		plan.Stop()
+	}
 }
 
 // consulLogger is a custom logger that forwards errors to the error function
@@ -209,32 +329,88 @@ func (l *consulLogger) Error(msg string, args ...interface{}) {
 	l.errFunc(errors.New(buf.String()))
 }
 
+// includer resolves include directives against the keys of the Consul KV store
+type includer struct {
+	r *Resource
+}
+
+// Join resolves an included key relative to the "directory" of the including key,
+// a leading "/" makes it relative to the root of the KV store
+func (i includer) Join(from string, name string) string {
+	if strings.HasPrefix(name, "/") {
+		return strings.TrimPrefix(path.Clean(name), "/")
+	}
+	return path.Join(path.Dir(from), name)
+}
+
+// Glob returns the keys matching a pattern
+func (i includer) Glob(ctx context.Context, pattern string) ([]string, error) {
+	keys, _, err := i.r.client.KV().Keys(globPrefix(pattern), "", new(api.QueryOptions).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, key := range keys {
+		if matched, _ := path.Match(pattern, key); matched {
+			names = append(names, key)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Fetch retrieves the value of an included key
+func (i includer) Fetch(ctx context.Context, name string) ([]byte, error) {
+	return i.r.load(name)(ctx)
+}
+
+// globPrefix returns the part of a pattern before its first special character
+func globPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
 // New creates a new Consul configuration resource
-// It validates the key extension and finds an appropriate formatter
+// It finds the formatter of the format set by resource.WithFormat or of the key extension;
+// without either, the format is detected from the content.
+// For every active profile, the profile-specific key (e.g. "app/config.prod.yaml" for "app/config.yaml")
+// is deep-merged on top of the configuration if it exists.
+// Include directives (see resource.IncludeKey) name keys relative to the including key, e.g. "common.yaml"
+// in "app/config.yaml" includes "app/common.yaml"; the included keys are watched as well.
 // Parameters:
 //   - client: Consul API client
 //   - key: Path to the configuration in Consul KV store
+//   - opts: Options such as resource.WithProfiles, resource.WithFormat and resource.WithRegistry
 //
 // Returns:
 //   - *Resource: New Consul resource instance
 //   - error: Any error during initialization
-func New(client *api.Client, key string) (*Resource, error) {
-	// Extract key extension
-	ext := strings.TrimPrefix(filepath.Ext(key), ".")
-	if ext == "" {
-		return nil, fmt.Errorf("config: key extension is empty")
-	}
+func New(client *api.Client, key string, opts ...resource.Option) (*Resource, error) {
+	options := resource.NewOptions(opts...)
 
-	// Find appropriate formatter for the key extension
-	formatter, ok := format.GetFormatter(ext)
-	if !ok {
-		return nil, fmt.Errorf("config: not found formatter for %s", ext)
+	// Find appropriate formatter for the explicit format or the key extension, or sniff the format
+	formatter, err := options.Formatter(key)
+	if err != nil {
+		return nil, err
 	}
 
 	// Return new resource instance
-	return &Resource{
-		client:    client,
-		key:       key,
-		formatter: formatter,
-	}, nil
+	r := &Resource{
+		client: client,
+		key:    key,
+		keys:   []string{key},
+	}
+	r.core = resource.NewCore("consul", r.load(key), formatter)
+	for _, profile := range options.Profiles {
+		profileKey := options.ProfileName(key, profile)
+		r.keys = append(r.keys, profileKey)
+		r.core.AddOverlay(r.load(profileKey))
+	}
+	r.core.EnableIncludes(includer{r: r}, options, r.keys...)
+	for _, transformer := range options.Transformers {
+		r.core.AddTransformer(transformer)
+	}
+	return r, nil
 }
@@ -0,0 +1,484 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrNotExist is reported by fetch functions when the source does not exist.
+// It is fs.ErrNotExist so that errors returned by the os package match it.
+var ErrNotExist = fs.ErrNotExist
+
+// FetchFunc defines the function type for retrieving the raw configuration data of a source.
+type FetchFunc func(ctx context.Context) ([]byte, error)
+
+// Core implements the behavior shared by resources that fetch raw bytes from a source:
+// parsing data with a formatter, merging overlays, suppressing unchanged payloads, compressed or not, and
+// validating watch arguments.
+// Resource implementations embed a Core and only implement fetching and change detection.
+type Core struct {
+	// name identifies the kind of source in log messages (e.g., "file", "consul")
+	name string
+	// formatter is used for parsing raw data into structured data
+	formatter format.Formatter
+	// mutex protects layers, messageType, the include settings and transformers
+	mutex sync.Mutex
+	// layers are the base source followed by its overlays
+	layers []*layer
+	// messageType is the type of the message set by LoadMessage or RegisterMessage, used by a format.MessageFormatter
+	messageType protoreflect.MessageType
+	// includer resolves include directives, nil if they are not resolved
+	includer Includer
+	// options provide the formatters of included sources
+	options *Options
+	// includeFormatters are the formatters of included sources by name
+	includeFormatters map[string]format.Formatter
+	// transformers transform the parsed data of every layer
+	transformers []Transformer
+	// notifying holds the snapshots of the layers of the values being notified, by value
+	notifying sync.Map
+}
+
+// layer is a single source of raw data merged by a Core
+type layer struct {
+	// fetch retrieves the raw configuration data
+	fetch FetchFunc
+	// optional reports whether the source may be missing
+	optional bool
+	// pre is the previous configuration data used to detect changes, nil if missing
+	pre []byte
+	// value is the parsed configuration data, nil if missing
+	value *structpb.Struct
+	// name is the name of the source, include directives resolve relative to it
+	name string
+	// includes are the names of the sources included by the data
+	includes []string
+	// patterns are the glob patterns of the include directives of the data
+	patterns []string
+	// sensitive holds the values the transformers marked as sensitive in the data
+	sensitive *format.Sensitive
+}
+
+// NewCore creates a new Core
+// Parameters:
+//   - name: Kind of source used in log messages
+//   - fetch: Function retrieving the raw configuration data
+//   - formatter: Formatter parsing the raw configuration data
+//
+// Returns:
+//   - *Core: New core instance
+func NewCore(name string, fetch FetchFunc, formatter format.Formatter) *Core {
+	return &Core{
+		name:      name,
+		formatter: formatter,
+		layers:    []*layer{{fetch: fetch}},
+	}
+}
+
+// AddOverlay adds an optional source whose data is deep-merged on top of the previous layers.
+// A source reporting ErrNotExist or empty data is skipped.
+// Parameters:
+//   - fetch: Function retrieving the raw configuration data of the overlay
+func (c *Core) AddOverlay(fetch FetchFunc) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.layers = append(c.layers, &layer{fetch: fetch, optional: true})
+}
+
+// AddTransformer adds a transformer applied to the parsed data of every layer, after include directives are resolved.
+// Transformers do not apply to data decoded directly into messages by a format.MessageFormatter.
+// Parameters:
+//   - transformer: Transformer to add
+func (c *Core) AddTransformer(transformer Transformer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.transformers = append(c.transformers, transformer)
+}
+
+// Load fetches, parses and merges the configuration data of every layer
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//
+// Returns:
+//   - *structpb.Struct: Parsed configuration data
+//   - error: Any error that occurred during fetching or parsing
+func (c *Core) Load(ctx context.Context) (*structpb.Struct, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, l := range c.layers {
+		data, err := c.fetchLayer(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.parseLayer(ctx, l, data); err != nil {
+			return nil, err
+		}
+	}
+	return c.merge(), nil
+}
+
+// LoadMessage fetches the configuration data of every layer and decodes it into a message.
+// If the formatter is a format.MessageFormatter, the data is decoded directly into the message and
+// overlays are merged with proto.Merge; the message type is also used to parse data from then on.
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during fetching or decoding
+func (c *Core) LoadMessage(ctx context.Context, message proto.Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.registerMessage(message)
+	for _, l := range c.layers {
+		data, err := c.fetchLayer(ctx, l)
+		if err != nil {
+			return err
+		}
+		if err := c.parseLayer(ctx, l, data); err != nil {
+			return err
+		}
+	}
+	return c.decode(message)
+}
+
+// RegisterMessage sets the type of the message the configuration data is decoded into,
+// so that a format.MessageFormatter parses data loaded or pushed while watching without a previous LoadMessage
+// Parameters:
+//   - message: Message of the type the configuration data is decoded into
+func (c *Core) RegisterMessage(message proto.Message) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.registerMessage(message)
+}
+
+// registerMessage sets the message type if the formatter decodes data directly into messages
+func (c *Core) registerMessage(message proto.Message) {
+	if _, ok := c.formatter.(format.MessageFormatter); ok {
+		c.messageType = message.ProtoReflect().Type()
+	}
+}
+
+// DecodeMessage decodes the configuration data of the latest load or notification into a message
+// Parameters:
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (c *Core) DecodeMessage(message proto.Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.decode(message)
+}
+
+// DecodeValue decodes a configuration returned by Load or notified by Watch into a message as DecodeMessage does.
+// While a value is being notified, it is decoded from the data of the layers it was merged from,
+// even if they have been reloaded since; other values are decoded from the data of the latest load or notification.
+// Parameters:
+//   - value: Configuration data returned by Load or notified by Watch
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (c *Core) DecodeValue(value *structpb.Struct, message proto.Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if layers, ok := c.notifying.Load(value); ok {
+		return c.decodeLayers(layers.([]*layer), value, message)
+	}
+	return c.decode(message)
+}
+
+// decode decodes the configuration data of every layer into a message
+func (c *Core) decode(message proto.Message) error {
+	return c.decodeLayers(c.layers, c.merge(), message)
+}
+
+// decodeLayers decodes the configuration data of layers, merged into value, into a message.
+// Errors are a *format.ParseError or a *format.ConvertError locating the offending field in its source,
+// values the transformers marked as sensitive are redacted.
+func (c *Core) decodeLayers(layers []*layer, value *structpb.Struct, message proto.Message) error {
+	formatter, ok := c.formatter.(format.MessageFormatter)
+	if !ok {
+		err := format.StructToMessage(value, message)
+		var convertErr *format.ConvertError
+		if errors.As(err, &convertErr) {
+			c.locate(layers, convertErr)
+			for _, l := range layers {
+				l.sensitive.Redact(err)
+			}
+		}
+		return err
+	}
+	proto.Reset(message)
+	for _, l := range layers {
+		if l.pre == nil {
+			continue
+		}
+		value := message.ProtoReflect().New().Interface()
+		if err := formatter.Unmarshal(l.pre, value); err != nil {
+			return parseError(l.name, err)
+		}
+		proto.Merge(message, value)
+	}
+	return nil
+}
+
+// locate sets the source and position of the field of a conversion error,
+// from the layer of highest priority providing the field if the formatter is a format.Locator
+func (c *Core) locate(layers []*layer, err *format.ConvertError) {
+	locator, ok := c.formatter.(format.Locator)
+	if !ok || err.Path == "" {
+		return
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+		if l.pre == nil {
+			continue
+		}
+		if position, ok := locator.Locate(l.pre, err.Path); ok {
+			err.Source, err.Position = l.name, position
+			return
+		}
+	}
+}
+
+// parseError attributes an error parsing the data of a source to the source,
+// the position is taken from protojson and prototext errors if the formatter has not set it
+func parseError(source string, err error) error {
+	var parseErr *format.ParseError
+	if errors.As(err, &parseErr) {
+		if parseErr.Source == "" {
+			parseErr.Source = source
+		}
+		return err
+	}
+	position, _ := format.ProtojsonPosition(err)
+	return &format.ParseError{Source: source, Position: position, Err: err}
+}
+
+// Reload fetches the configuration data of every layer and notifies subscribers if it has changed
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+func (c *Core) Reload(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) {
+	c.reload(ctx, false, notifyFunc, errFunc)
+}
+
+// Refresh fetches and parses the configuration data of every layer even if it is unchanged,
+// and notifies subscribers if the result has changed.
+// It is used when data the formatter depends on, such as imported or included files, has changed.
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+func (c *Core) Refresh(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) {
+	c.reload(ctx, true, notifyFunc, errFunc)
+}
+
+// reload fetches the configuration data of every layer and notifies subscribers if it has changed,
+// force re-parses layers whose raw data is unchanged
+func (c *Core) reload(ctx context.Context, force bool, notifyFunc NotifyFunc, errFunc ErrFunc) {
+	c.mutex.Lock()
+	var preValue *structpb.Struct
+	if force {
+		preValue = c.merge()
+	}
+	changed := false
+	for _, l := range c.layers {
+		data, err := c.fetchLayer(ctx, l)
+		if err != nil {
+			c.mutex.Unlock()
+			errFunc(err)
+			return
+		}
+		// Compare with previous data to avoid unnecessary parsing
+		if !force && l.pre != nil && format.EqualDecompressed(l.pre, data) {
+			continue
+		}
+		if l.optional && l.pre == nil && data == nil {
+			continue
+		}
+		if err := c.parseLayer(ctx, l, data); err != nil {
+			c.mutex.Unlock()
+			errFunc(err)
+			return
+		}
+		changed = true
+	}
+	if !changed {
+		c.mutex.Unlock()
+		return
+	}
+	newValue, layers := c.merge(), c.snapshot()
+	c.mutex.Unlock()
+	// Re-parsed data may be unchanged
+	if force && proto.Equal(preValue, newValue) {
+		return
+	}
+	// Notify subscribers of the change
+	c.notify(newValue, layers, notifyFunc)
+}
+
+// Notify parses data pushed by the base source and notifies subscribers if it has changed
+// Parameters:
+//   - data: Raw configuration data
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+func (c *Core) Notify(data []byte, notifyFunc NotifyFunc, errFunc ErrFunc) {
+	c.NotifyLayer(0, data, notifyFunc, errFunc)
+}
+
+// NotifyLayer parses data pushed by the source of the layer at index i
+// and notifies subscribers if it has changed
+// Parameters:
+//   - i: Index of the layer, 0 is the base source and overlays follow in the order they were added
+//   - data: Raw configuration data, empty data marks an overlay as missing
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+func (c *Core) NotifyLayer(i int, data []byte, notifyFunc NotifyFunc, errFunc ErrFunc) {
+	c.mutex.Lock()
+	l := c.layers[i]
+	if l.optional && len(data) == 0 {
+		data = nil
+	}
+	// Compare with previous data to avoid unnecessary notifications
+	if (l.pre != nil && format.EqualDecompressed(l.pre, data)) || (l.optional && l.pre == nil && data == nil) {
+		c.mutex.Unlock()
+		return
+	}
+	// Parse new configuration data
+	if err := c.parseLayer(context.Background(), l, data); err != nil {
+		c.mutex.Unlock()
+		errFunc(err)
+		return
+	}
+	newValue, layers := c.merge(), c.snapshot()
+	c.mutex.Unlock()
+	// Notify subscribers of the change
+	c.notify(newValue, layers, notifyFunc)
+}
+
+// snapshot copies the state of the layers, so that a notified value can be decoded after they are reloaded
+func (c *Core) snapshot() []*layer {
+	layers := make([]*layer, len(c.layers))
+	for i, l := range c.layers {
+		state := *l
+		layers[i] = &state
+	}
+	return layers
+}
+
+// notify notifies subscribers of a value, DecodeValue decodes it from the layers it was merged from meanwhile
+func (c *Core) notify(value *structpb.Struct, layers []*layer, notifyFunc NotifyFunc) {
+	c.notifying.Store(value, layers)
+	defer c.notifying.Delete(value)
+	notifyFunc(value)
+}
+
+// fetchLayer retrieves the raw data of a layer, nil data marks a missing optional layer
+func (c *Core) fetchLayer(ctx context.Context, l *layer) ([]byte, error) {
+	data, err := l.fetch(ctx)
+	if !l.optional {
+		return data, err
+	}
+	if errors.Is(err, ErrNotExist) || (err == nil && len(data) == 0) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// parseLayer parses the raw data of a layer, resolves its include directives and stores it for future comparisons
+func (c *Core) parseLayer(ctx context.Context, l *layer, data []byte) error {
+	if data == nil && l.optional {
+		l.pre, l.value, l.includes, l.patterns, l.sensitive = nil, nil, nil, nil, nil
+		return nil
+	}
+	value, err := c.parse(data)
+	if err != nil {
+		return parseError(l.name, err)
+	}
+	var state includeState
+	if c.includer != nil && value != nil {
+		state.ctx = ctx
+		if value, err = c.include(&state, []string{l.name}, value); err != nil {
+			// Keep watching the sources included so far, fixing them may resolve the error
+			l.includes, l.patterns = mergeNames(l.includes, state.includes), mergeNames(l.patterns, state.patterns)
+			return err
+		}
+	}
+	// Collect the values marked as sensitive by the transformers of this parse only
+	sensitive := &format.Sensitive{}
+	transformCtx := format.ContextWithSensitive(ctx, sensitive)
+	for _, transformer := range c.transformers {
+		if value == nil {
+			break
+		}
+		if value, err = transformer.Transform(transformCtx, value); err != nil {
+			return err
+		}
+	}
+	// Store new data for future comparisons
+	l.pre, l.value, l.includes, l.patterns, l.sensitive = data, value, state.includes, state.patterns, sensitive
+	return nil
+}
+
+// parse parses raw data, decoding it into a message first if the message type is known
+func (c *Core) parse(data []byte) (*structpb.Struct, error) {
+	formatter, ok := c.formatter.(format.MessageFormatter)
+	if !ok || c.messageType == nil {
+		return c.formatter.Parse(data)
+	}
+	message := c.messageType.New().Interface()
+	if err := formatter.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	return format.StructFromMessage(message)
+}
+
+// merge deep-merges the values of every layer
+func (c *Core) merge() *structpb.Struct {
+	if len(c.layers) == 1 {
+		return c.layers[0].value
+	}
+	values := make([]*structpb.Struct, 0, len(c.layers))
+	for _, l := range c.layers {
+		values = append(values, l.value)
+	}
+	return Merge(values...)
+}
+
+// PrepareWatch validates the arguments of Watch, the default error handler names the kind of source of the core
+// Parameters:
+//   - ctx: Context for cancellation
+//   - notifyFunc: Callback function for configuration updates, must not be nil
+//   - errFunc: Callback function for error reporting, a logging default is used if nil
+//
+// Returns:
+//   - ErrFunc: Error handler to use while watching
+//   - error: Error if notifyFunc is nil or the context is already cancelled
+func (c *Core) PrepareWatch(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (ErrFunc, error) {
+	return PrepareWatch(ctx, c.name, notifyFunc, errFunc)
+}
+
+// NewStopFunc creates a StopFunc that closes the returned channel exactly once
+// Returns:
+//   - StopFunc: Function to stop watching
+//   - <-chan struct{}: Channel closed when the StopFunc is called
+func NewStopFunc() (StopFunc, <-chan struct{}) {
+	stopC := make(chan struct{})
+	var onceStop sync.Once
+	stop := func(ctx context.Context) error {
+		onceStop.Do(func() { close(stopC) })
+		return nil
+	}
+	return stop, stopC
+}
@@ -0,0 +1,182 @@
+package resource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/binpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// keyFormatter parses data into a struct with a single "data" field
+type keyFormatter struct{}
+
+func (keyFormatter) Parse(data []byte) (*structpb.Struct, error) {
+	if string(data) == "invalid" {
+		return nil, errors.New("invalid data")
+	}
+	return structpb.NewStruct(map[string]any{"data": string(data)})
+}
+
+func TestCore_Load(t *testing.T) {
+	core := NewCore("test", func(ctx context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	}, keyFormatter{})
+	value, err := core.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["data"].GetStringValue(); got != "value" {
+		t.Errorf("expected 'value'; got %q", got)
+	}
+}
+
+func TestCore_Notify(t *testing.T) {
+	core := NewCore("test", nil, keyFormatter{})
+	var notified []string
+	var errs []error
+	notifyFunc := func(value *structpb.Struct) {
+		notified = append(notified, value.GetFields()["data"].GetStringValue())
+	}
+	errFunc := func(err error) {
+		errs = append(errs, err)
+	}
+
+	core.Notify([]byte("a"), notifyFunc, errFunc)
+	core.Notify([]byte("a"), notifyFunc, errFunc)
+	core.Notify([]byte("invalid"), notifyFunc, errFunc)
+	core.Notify([]byte("b"), notifyFunc, errFunc)
+
+	if len(notified) != 2 || notified[0] != "a" || notified[1] != "b" {
+		t.Errorf("expected notifications [a b]; got %v", notified)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error; got %v", errs)
+	}
+}
+
+func TestCore_PrepareWatch(t *testing.T) {
+	core := NewCore("test", nil, keyFormatter{})
+	if _, err := core.PrepareWatch(context.Background(), nil, nil); err == nil {
+		t.Error("expected error for nil notifyFunc")
+	}
+	errFunc, err := core.PrepareWatch(context.Background(), func(*structpb.Struct) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errFunc == nil {
+		t.Error("expected default errFunc")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := core.PrepareWatch(ctx, func(*structpb.Struct) {}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+}
+
+func TestCore_DecodeValue(t *testing.T) {
+	// binpb decodes the raw data of the layers, not the notified value
+	core := NewCore("test", nil, binpb.Binpb{})
+	core.RegisterMessage(&descriptorpb.UninterpretedOption{})
+	data := func(identifier string) []byte {
+		data, _ := proto.Marshal(&descriptorpb.UninterpretedOption{IdentifierValue: proto.String(identifier)})
+		return data
+	}
+	var decoded []string
+	errFunc := func(err error) {
+		t.Error(err)
+	}
+	var notifyFunc NotifyFunc
+	notifyFunc = func(value *structpb.Struct) {
+		// A change racing with the notification must not change the decoded configuration
+		if value.GetFields()["identifierValue"].GetStringValue() == "a" {
+			core.Notify(data("b"), notifyFunc, errFunc)
+		}
+		message := &descriptorpb.UninterpretedOption{}
+		if err := core.DecodeValue(value, message); err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, message.GetIdentifierValue())
+	}
+	core.Notify(data("a"), notifyFunc, errFunc)
+	if len(decoded) != 2 || decoded[0] != "b" || decoded[1] != "a" {
+		t.Errorf("expected decoded [b a]; got %v", decoded)
+	}
+
+	// Values not being notified are decoded from the latest data
+	message := &descriptorpb.UninterpretedOption{}
+	if err := core.DecodeValue(&structpb.Struct{}, message); err != nil {
+		t.Fatal(err)
+	}
+	if got := message.GetIdentifierValue(); got != "b" {
+		t.Errorf("expected 'b'; got %q", got)
+	}
+}
+
+func TestCore_LoadMessage(t *testing.T) {
+	// -(1<<60+1) cannot be represented by the float64 numbers of a Struct
+	base, _ := proto.Marshal(&descriptorpb.UninterpretedOption{NegativeIntValue: proto.Int64(-(1<<60 + 1))})
+	core := NewCore("test", func(ctx context.Context) ([]byte, error) {
+		return base, nil
+	}, binpb.Binpb{})
+	message := &descriptorpb.UninterpretedOption{}
+	if err := core.LoadMessage(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	if message.GetNegativeIntValue() != -(1<<60 + 1) {
+		t.Errorf("expected %d; got %d", -(1<<60 + 1), message.GetNegativeIntValue())
+	}
+
+	// Pushed data is parsed with the message type of LoadMessage
+	var notified *structpb.Struct
+	changed, _ := proto.Marshal(&descriptorpb.UninterpretedOption{NegativeIntValue: proto.Int64(-(1<<60 + 2))})
+	core.Notify(changed, func(value *structpb.Struct) { notified = value }, func(err error) { t.Error(err) })
+	if notified == nil {
+		t.Fatal("expected notification")
+	}
+	if err := core.DecodeMessage(message); err != nil {
+		t.Fatal(err)
+	}
+	if message.GetNegativeIntValue() != -(1<<60 + 2) {
+		t.Errorf("expected %d; got %d", -(1<<60 + 2), message.GetNegativeIntValue())
+	}
+}
+
+func TestCore_Notify_Compressed(t *testing.T) {
+	compress := func(data string, modTime time.Time) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.ModTime = modTime
+		_, _ = w.Write([]byte(data))
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	formatter, err := format.WithDecompression("")(keyFormatter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := NewCore("test", nil, formatter)
+	var notified []string
+	notifyFunc := func(value *structpb.Struct) {
+		notified = append(notified, value.GetFields()["data"].GetStringValue())
+	}
+	errFunc := func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Recompressing unchanged data only changes the gzip header
+	core.Notify(compress("a", time.Unix(1, 0)), notifyFunc, errFunc)
+	core.Notify(compress("a", time.Unix(2, 0)), notifyFunc, errFunc)
+	core.Notify(compress("b", time.Unix(3, 0)), notifyFunc, errFunc)
+
+	if len(notified) != 2 || notified[0] != "a" || notified[1] != "b" {
+		t.Errorf("expected notifications [a b]; got %v", notified)
+	}
+}
@@ -0,0 +1,361 @@
+// Package dir provides a configuration resource merging the fragments of a conf.d directory
+package dir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ resource.Resource = (*Resource)(nil)
+
+// Resource represents a configuration deep-merged from the files of a directory
+type Resource struct {
+	// dir is the path of the directory
+	dir string
+	// pattern filters the names of the files, empty for every supported file
+	pattern string
+	// compare orders the names of the files, later files override earlier ones
+	compare func(a, b string) int
+	// options provide the formatters, includes and transformers of the files
+	options *resource.Options
+	// tracker serializes loads and remembers the latest merged configuration data, used to detect changes
+	tracker resource.Tracker
+	// mutex protects cores
+	mutex sync.Mutex
+	// cores parse the files by path
+	cores map[string]*resource.Core
+}
+
+// Load reads, parses and deep-merges the files of the directory in order
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//
+// Returns:
+//   - *structpb.Struct: Merged configuration data, empty if the directory has no files
+//   - error: Any error that occurred while listing the directory or loading a file
+func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
+	return r.tracker.Load(ctx, r.load)
+}
+
+// load loads and merges the files currently in the directory, forgetting the files removed since the last load
+func (r *Resource) load(ctx context.Context) (*structpb.Struct, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	filenames, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+	cores := make(map[string]*resource.Core, len(filenames))
+	values := make([]*structpb.Struct, 0, len(filenames))
+	for _, filename := range filenames {
+		core, err := r.core(filename)
+		if err != nil {
+			return nil, err
+		}
+		value, err := core.Load(ctx)
+		if err != nil {
+			// A file removed since the directory was listed is skipped
+			if _, statErr := os.Stat(filename); errors.Is(statErr, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		cores[filename] = core
+		values = append(values, value)
+	}
+	r.cores = cores
+	return resource.Merge(values...), nil
+}
+
+// files returns the paths of the files of the directory to merge, in order.
+// Hidden files, directories and files without a formatter are skipped.
+func (r *Resource) files() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !r.matches(name) {
+			continue
+		}
+		// Follow symbolic links, e.g. of fragments installed by packages
+		info, err := os.Stat(filepath.Join(r.dir, name))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.SortFunc(names, r.compare)
+	filenames := make([]string, 0, len(names))
+	for _, name := range names {
+		filenames = append(filenames, filepath.Join(r.dir, name))
+	}
+	return filenames, nil
+}
+
+// matches reports whether a file name is a fragment: not hidden, matching the pattern and with a formatter
+func (r *Resource) matches(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	if r.pattern != "" {
+		if matched, _ := filepath.Match(r.pattern, name); !matched {
+			return false
+		}
+	}
+	if r.options.Format != "" {
+		return true
+	}
+	ext := r.options.Ext(name)
+	if ext == "" {
+		return false
+	}
+	_, ok := r.options.FormatRegistry().Get(ext)
+	return ok
+}
+
+// hiddenLink reports whether a hidden entry is a symbolic link,
+// entries that were removed or renamed away cannot be inspected and are assumed to be links
+func hiddenLink(name string) bool {
+	if !strings.HasPrefix(filepath.Base(name), ".") {
+		return false
+	}
+	info, err := os.Lstat(name)
+	return err != nil || info.Mode()&fs.ModeSymlink != 0
+}
+
+// core returns the core parsing a file, created on first use with the formatter of its extension
+func (r *Resource) core(filename string) (*resource.Core, error) {
+	if core, ok := r.cores[filename]; ok {
+		return core, nil
+	}
+	formatter, err := r.options.Formatter(filename)
+	if err != nil {
+		return nil, err
+	}
+	if sourceFormatter, ok := formatter.(format.SourceFormatter); ok {
+		formatter = sourceFormatter.WithSource(filename)
+	}
+	core := resource.NewCore("dir", func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(filename)
+	}, formatter)
+	core.EnableIncludes(resource.FileIncluder{}, r.options, filename)
+	for _, transformer := range r.options.Transformers {
+		core.AddTransformer(transformer)
+	}
+	return core, nil
+}
+
+// includes returns the files included by the fragments and the patterns of their include directives
+func (r *Resource) includes() ([]string, []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var includes, patterns []string
+	for _, core := range r.cores {
+		names, globs := core.Includes()
+		includes = append(includes, names...)
+		patterns = append(patterns, globs...)
+	}
+	return includes, patterns
+}
+
+// Watch monitors the directory with a single watcher and reloads the configuration
+// when a fragment is added, changed, removed or renamed, or when a file it includes changes.
+// Fragments reached through symbolic links are reloaded when a hidden link of the directory changes,
+// such as the "..data" link swapped by Kubernetes to update a mounted ConfigMap
+// Parameters:
+//   - ctx: Context for cancellation
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+//
+// Returns:
+//   - resource.StopFunc: Function to stop watching
+//   - error: Any immediate error during setup
+func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
+	// Validate arguments and set default error handler
+	errFunc, err := resource.PrepareWatch(ctx, "dir", notifyFunc, errFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize filesystem watcher
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// Watch the directory of the fragments
+	dir := filepath.Clean(r.dir)
+	dirs := map[string]bool{dir: true}
+	if err := fsWatcher.Add(dir); err != nil {
+		_ = fsWatcher.Close()
+		return nil, err
+	}
+
+	// Watch the directories of the included files and of the include patterns
+	watchDir := func(dir string) {
+		if dirs[dir] {
+			return
+		}
+		if err := fsWatcher.Add(dir); err != nil {
+			errFunc(err)
+			return
+		}
+		dirs[dir] = true
+	}
+	watchIncludes := func() ([]string, []string) {
+		includes, patterns := r.includes()
+		for _, include := range includes {
+			watchDir(filepath.Dir(include))
+		}
+		for _, pattern := range patterns {
+			if dir := filepath.Dir(pattern); !strings.ContainsAny(dir, "*?[") {
+				watchDir(dir)
+			}
+		}
+		return includes, patterns
+	}
+	includes, patterns := watchIncludes()
+
+	// Create stop function
+	stop, stopC := resource.NewStopFunc()
+
+	// Start watching in a separate goroutine
+	go func() {
+		// Ensure watcher is closed when goroutine exits
+		defer func() {
+			if err := fsWatcher.Close(); err != nil {
+				errFunc(err)
+			}
+		}()
+
+		// Event loop
+		for {
+			select {
+			case <-ctx.Done():
+				// Context cancelled, exit goroutine
+				errFunc(ctx.Err())
+				return
+
+			case <-stopC:
+				// Stop signal received, exit goroutine
+				return
+
+			case err, ok := <-fsWatcher.Errors:
+				// Error from filesystem watcher
+				if !ok {
+					return
+				}
+				errFunc(err)
+
+			case event, ok := <-fsWatcher.Events:
+				// File system event received
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				switch {
+				case filepath.Dir(name) == dir && r.matches(filepath.Base(name)):
+					// A fragment was added, changed, removed or renamed
+				case filepath.Dir(name) == dir && hiddenLink(name):
+					// A hidden symbolic link was created, replaced or removed, e.g. the "..data" link swapped
+					// by the atomic writer of the kubelet, so the fragments may now resolve to other targets
+				case slices.Contains(includes, name):
+					// An included file changed
+				case slices.ContainsFunc(patterns, func(pattern string) bool { return resource.MatchPattern(pattern, name) }):
+					// A file matching an include pattern was added, changed or removed
+				default:
+					continue
+				}
+				// Unchanged configurations are not notified
+				r.tracker.Reload(ctx, r.load, notifyFunc, errFunc)
+				// The fragments may include other files
+				includes, patterns = watchIncludes()
+			}
+		}
+	}()
+
+	return stop, nil
+}
+
+// options holds the settings of the resource
+type options struct {
+	compare   func(a, b string) int
+	resources []resource.Option
+}
+
+// Option defines the function type for configuring the resource.
+type Option func(*options)
+
+// WithCompare sets the order of the files instead of the lexical order of their names,
+// later files override earlier ones.
+// Parameters:
+//   - compare: Function comparing two file names as slices.SortFunc expects, e.g. to order by a numeric prefix
+func WithCompare(compare func(a, b string) int) Option {
+	return func(o *options) {
+		o.compare = compare
+	}
+}
+
+// WithOptions sets the options applied to every file, such as resource.WithRegistry,
+// resource.WithFormat, resource.WithTransformers or resource.WithTemplate.
+// Profiles are ignored, name profile-specific fragments so that they sort after the fragments they override.
+// Parameters:
+//   - opts: Options of the files
+func WithOptions(opts ...resource.Option) Option {
+	return func(o *options) {
+		o.resources = append(o.resources, opts...)
+	}
+}
+
+// New creates a new directory configuration resource
+// The path is a directory, e.g. "/etc/app/conf.d", or a pattern of file names in a directory,
+// e.g. "/etc/app/conf.d/*.yaml". Every file with a registered format is parsed with the formatter of its
+// extension, so that formats can be mixed, and the files are deep-merged in the lexical order of their names.
+// Hidden files, e.g. editor swap files, are skipped. Include directives resolve against the directory of the
+// including file. A missing directory is reported when loading.
+// Parameters:
+//   - path: Path of the directory, or pattern of the file names
+//   - opts: Options such as WithCompare and WithOptions
+//
+// Returns:
+//   - *Resource: New directory resource instance
+//   - error: Error if the pattern is malformed
+func New(path string, opts ...Option) (*Resource, error) {
+	o := &options{compare: strings.Compare}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Split the pattern of the file names from the directory
+	dir, pattern := path, ""
+	if base := filepath.Base(path); strings.ContainsAny(base, "*?[") {
+		dir, pattern = filepath.Dir(path), base
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("config: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Return new resource instance
+	return &Resource{
+		dir:     dir,
+		pattern: pattern,
+		compare: o.compare,
+		options: resource.NewOptions(o.resources...),
+		cores:   make(map[string]*resource.Core),
+	}, nil
+}
@@ -0,0 +1,270 @@
+package dir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/soyacen/gonfig/format/json"
+	_ "github.com/soyacen/gonfig/format/yaml"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// writeFiles writes files into a directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		expectedDir     string
+		expectedPattern string
+		expectErr       string
+	}{
+		{"Directory", "/etc/app/conf.d", "/etc/app/conf.d", "", ""},
+		{"Pattern", "/etc/app/conf.d/*.yaml", "/etc/app/conf.d", "*.yaml", ""},
+		{"Malformed Pattern", "/etc/app/conf.d/[.yaml", "", "", "config: invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, err := New(tt.path)
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Errorf("expected error %q; got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resource.dir != tt.expectedDir || resource.pattern != tt.expectedPattern {
+				t.Errorf("expected %q and %q; got %q and %q", tt.expectedDir, tt.expectedPattern, resource.dir, resource.pattern)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"10-base.yaml":      "redis:\n  host: localhost\n  port: 6379\nlevels: [info]\n",
+		"20-pkg.json":       `{"redis": {"port": 6380}, "feature": true}`,
+		"30-site.yaml":      "redis:\n  host: redis.local\n",
+		".30-site.yaml.swp": "redis: {host: swap}\n",
+		"README.txt":        "not a fragment",
+	})
+	if err := os.Mkdir(filepath.Join(tempDir, "99-dir.yaml"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	resource, err := New(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"redis":   map[string]any{"host": "redis.local", "port": float64(6380)},
+		"levels":  []any{"info"},
+		"feature": true,
+	}
+	if !reflect.DeepEqual(expected, value.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, value.AsMap())
+	}
+}
+
+func TestLoad_Pattern(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"a.yaml": "name: a\n",
+		"b.json": `{"name": "b"}`,
+	})
+
+	resource, err := New(filepath.Join(tempDir, "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["name"].GetStringValue(); got != "a" {
+		t.Errorf("expected a; got %q", got)
+	}
+}
+
+func TestLoad_Compare(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"a.yaml": "name: a\n",
+		"b.yaml": "name: b\n",
+	})
+
+	// Reverse order, the first name overrides
+	resource, err := New(tempDir, WithCompare(func(a, b string) int { return strings.Compare(b, a) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["name"].GetStringValue(); got != "a" {
+		t.Errorf("expected a; got %q", got)
+	}
+}
+
+func TestLoad_Empty(t *testing.T) {
+	resource, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value.GetFields()) != 0 {
+		t.Errorf("expected empty struct; got %v", value.AsMap())
+	}
+
+	resource, err = New(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(context.Background()); err == nil {
+		t.Error("expected error for missing directory, got nil")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{"10-base.yaml": "name: base\nport: 80\n"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	notifyFunc := func(newValue *structpb.Struct) {
+		c <- newValue
+	}
+	errFunc := func(err error) {
+		// A fragment may be read while it is being written
+		t.Logf("Error: %v", err)
+	}
+	stop, err := resource.Watch(ctx, notifyFunc, errFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// wait waits for the merged configuration to become expected
+	wait := func(expected map[string]any) {
+		t.Helper()
+		for {
+			select {
+			case value := <-c:
+				if reflect.DeepEqual(expected, value.AsMap()) {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("expected %v; got timeout", expected)
+			}
+		}
+	}
+
+	// Add a fragment of another format
+	writeFiles(t, tempDir, map[string]string{"20-pkg.json": `{"name": "pkg"}`})
+	wait(map[string]any{"name": "pkg", "port": float64(80)})
+
+	// Rename a fragment so that it sorts first
+	if err := os.Rename(filepath.Join(tempDir, "20-pkg.json"), filepath.Join(tempDir, "00-pkg.json")); err != nil {
+		t.Fatal(err)
+	}
+	wait(map[string]any{"name": "base", "port": float64(80)})
+
+	// Remove a fragment
+	if err := os.Remove(filepath.Join(tempDir, "10-base.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	wait(map[string]any{"name": "pkg"})
+}
+
+func TestWatch_ConfigMap(t *testing.T) {
+	// Lay out a directory as the kubelet mounts a ConfigMap: fragments are links through the "..data" link
+	// to a timestamped directory, which is swapped atomically on updates
+	tempDir := t.TempDir()
+	for _, version := range []string{"..v1", "..v2"} {
+		if err := os.Mkdir(filepath.Join(tempDir, version), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFiles(t, filepath.Join(tempDir, "..v1"), map[string]string{"10-base.yaml": "name: v1\n"})
+	if err := os.Symlink("..v1", filepath.Join(tempDir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "10-base.yaml"), filepath.Join(tempDir, "10-base.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		c <- newValue
+	}, func(err error) {
+		t.Logf("Error: %v", err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// Swap the "..data" link to a new version
+	writeFiles(t, filepath.Join(tempDir, "..v2"), map[string]string{"10-base.yaml": "name: v2\n"})
+	if err := os.Symlink("..v2", filepath.Join(tempDir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(tempDir, "..data_tmp"), filepath.Join(tempDir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case value := <-c:
+			if value.GetFields()["name"].GetStringValue() == "v2" {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("expected notification of v2; got timeout")
+		}
+	}
+}
@@ -5,17 +5,15 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"log/slog"
 	"os"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	"golang.org/x/exp/slices"
 
-	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/env"
 	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -27,10 +25,8 @@ type Resource struct {
 	prefix string
 	// interval defines how often to check for environment variable changes
 	interval time.Duration
-	// formatter is used for parsing environment variables into structured data
-	formatter format.Formatter
-	// pre is atomic storage for previous configuration data to detect changes
-	pre atomic.Value
+	// core parses environment variables and suppresses unchanged payloads
+	core *resource.Core
 }
 
 // Load retrieves and parses environment variables with the specified prefix
@@ -43,16 +39,7 @@ type Resource struct {
 //   - *structpb.Struct: Parsed configuration data
 //   - error: Any error that occurred during loading or parsing
 func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
-	data, err := r.load(ctx)
-	if err != nil {
-		return nil, err
-	}
-	parsed, err := r.formatter.Parse(data)
-	if err != nil {
-		return nil, err
-	}
-	r.pre.Store(data)
-	return parsed, nil
+	return r.core.Load(ctx)
 }
 
 // load collects and prepares environment variables data
@@ -92,30 +79,14 @@ func (r *Resource) load(ctx context.Context) ([]byte, error) {
 //   - resource.StopFunc: Function to stop watching
 //   - error: Any immediate error during setup
 func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
-	// Validate notify function
-	if notifyFunc == nil {
-		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
-	}
-
-	// Set default error handler if none provided
-	if errFunc == nil {
-		errFunc = func(err error) {
-			slog.Error("gonfig: failed to watch env", slog.String("error", err.Error()))
-		}
-	}
-
-	// Check if context is already cancelled
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// Validate arguments and set default error handler
+	errFunc, err := r.core.PrepareWatch(ctx, notifyFunc, errFunc)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create stop function with sync.Once to ensure it's only called once
-	stopC := make(chan struct{})
-	var onceStop sync.Once
-	stop := func(ctx context.Context) error {
-		onceStop.Do(func() { close(stopC) })
-		return nil
-	}
+	// Create stop function
+	stop, stopC := resource.NewStopFunc()
 
 	// Start watching in a separate goroutine
 	go func() {
@@ -132,26 +103,7 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 
 			case <-time.After(r.interval):
 				// Check for changes at regular intervals
-				data, err := r.load(ctx)
-				if err != nil {
-					errFunc(err)
-					continue
-				}
-				// Compare with previous data to avoid unnecessary notifications
-				preData := r.pre.Load()
-				if preData != nil && bytes.Equal(preData.([]byte), data) {
-					continue // Skip if no changes
-				}
-				// Parse new configuration data
-				newValue, err := r.formatter.Parse(data)
-				if err != nil {
-					errFunc(err)
-					continue
-				}
-				// Notify subscribers of the change
-				notifyFunc(newValue)
-				// Store new data for future comparisons
-				r.pre.Store(data)
+				r.core.Reload(ctx, notifyFunc, errFunc)
 			}
 		}
 	}()
@@ -159,32 +111,106 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 	return stop, nil
 }
 
+// options holds the key mapping settings of the resource and the options shared with the other resources
+type options struct {
+	separator string
+	message   protoreflect.MessageDescriptor
+	resources []resource.Option
+}
+
+// Option defines the function type for configuring the resource.
+type Option func(*options)
+
+// WithSeparator enables the key mapping of the env formatter: the prefix is stripped, variable names
+// are split into nested fields at the separator, case-folded to proto field names, and numeric
+// segments become list indices, e.g. APP_REDIS__ADDR populates redis.addr for the prefix "APP_" and separator "__".
+// Parameters:
+//   - separator: Separator of nested fields, e.g. "__" or "_"
+func WithSeparator(separator string) Option {
+	return func(o *options) {
+		o.separator = separator
+	}
+}
+
+// WithMessage enables the key mapping of the env formatter and resolves variable names against the
+// fields of the config message, so that "_" can separate nested fields whose names contain underscores.
+// Parameters:
+//   - message: Descriptor of the config message, e.g. (&configs.Config{}).ProtoReflect().Descriptor()
+func WithMessage(message protoreflect.MessageDescriptor) Option {
+	return func(o *options) {
+		o.message = message
+	}
+}
+
+// WithOptions sets the options shared with the other resources, such as resource.WithRegistry,
+// resource.WithTransformers, resource.WithTemplate or resource.WithFormatOptions.
+// Profiles are ignored, environment variables have no profile-specific variants.
+// Parameters:
+//   - opts: Options of the resource
+func WithOptions(opts ...resource.Option) Option {
+	return func(o *options) {
+		o.resources = append(o.resources, opts...)
+	}
+}
+
 // New creates a new environment variable configuration resource
 // It sets up a resource that will monitor environment variables with the given prefix
 // Parameters:
 //   - prefix: The prefix used to filter environment variables (e.g., "APP_")
 //   - interval: How often to check for changes (minimum 1 second)
+//   - opts: Options such as WithSeparator, WithMessage and WithOptions
 //
 // Returns:
 //   - *Resource: New environment variable resource instance
 //   - error: Any error during initialization
-func New(prefix string, interval time.Duration) (*Resource, error) {
+func New(prefix string, interval time.Duration, opts ...Option) (*Resource, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	resourceOptions := resource.NewOptions(o.resources...)
+
 	ext := "env"
+	if resourceOptions.Format != "" {
+		ext = resourceOptions.Format
+	}
 	// Find appropriate formatter for environment variables
-	formatter, ok := format.GetFormatter(ext)
+	formatter, ok := resourceOptions.FormatRegistry().Get(ext)
 	if !ok {
 		return nil, fmt.Errorf("config: not found formatter for %s", ext)
 	}
 
+	// Configure the key mapping of the formatter
+	if o.separator != "" || o.message != nil {
+		envFormatter, ok := formatter.(env.Env)
+		if !ok {
+			return nil, fmt.Errorf("config: key mapping is not supported by formatter %T", formatter)
+		}
+		envFormatter.Prefix = prefix
+		envFormatter.Separator = o.separator
+		envFormatter.Message = o.message
+		formatter = envFormatter
+	}
+
+	// Apply the format options, e.g. resource.WithTemplate
+	formatter, err := resourceOptions.WrapFormatter(formatter, "")
+	if err != nil {
+		return nil, err
+	}
+
 	// Set default interval if not provided or invalid
 	if interval <= 0 {
 		interval = 5 * time.Second
 	}
 
 	// Return new resource instance
-	return &Resource{
-		prefix:    prefix,
-		interval:  interval,
-		formatter: formatter,
-	}, nil
+	r := &Resource{
+		prefix:   prefix,
+		interval: interval,
+	}
+	r.core = resource.NewCore("env", r.load, formatter)
+	for _, transformer := range resourceOptions.Transformers {
+		r.core.AddTransformer(transformer)
+	}
+	return r, nil
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/soyacen/gonfig/format"
 	"github.com/soyacen/gonfig/format/env"
+	gonfigresource "github.com/soyacen/gonfig/resource"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -97,3 +98,68 @@ func TestWatch(t *testing.T) {
 		t.Errorf("expected value 'updated'; got %q", val)
 	}
 }
+
+func TestLoad_KeyMapping(t *testing.T) {
+	resource, err := New("MAPPED_", time.Second, WithSeparator("__"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MAPPED_REDIS__ADDR", "localhost:6379")
+	t.Setenv("MAPPED_HOSTS__0", "a")
+
+	data, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"redis": map[string]any{"addr": "localhost:6379"},
+		"hosts": []any{"a"},
+	}
+	if !reflect.DeepEqual(data.AsMap(), expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, data.AsMap())
+	}
+}
+
+// upperTransformer upper-cases the string values of the top-level fields
+type upperTransformer struct{}
+
+func (upperTransformer) Transform(_ context.Context, value *structpb.Struct) (*structpb.Struct, error) {
+	fields := make(map[string]*structpb.Value, len(value.GetFields()))
+	for key, field := range value.GetFields() {
+		fields[key] = structpb.NewStringValue(strings.ToUpper(field.GetStringValue()))
+	}
+	return &structpb.Struct{Fields: fields}, nil
+}
+
+func TestLoad_Options(t *testing.T) {
+	registry := format.NewRegistry()
+	if err := registry.Register("env", env.Env{}); err != nil {
+		t.Fatal(err)
+	}
+	resource, err := New("OPTIONS_", time.Second,
+		WithSeparator("__"),
+		WithOptions(
+			gonfigresource.WithRegistry(registry),
+			gonfigresource.WithTemplate(nil),
+			gonfigresource.WithTransformers(upperTransformer{}),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPTIONS_NAME", `{{ print "api" }}`)
+
+	data, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := data.GetFields()["name"].GetStringValue(); name != "API" {
+		t.Errorf("expected name 'API'; got %q", name)
+	}
+}
+
+func TestNew_FormatOptions(t *testing.T) {
+	if _, err := New("OPTIONS_", time.Second, WithOptions(gonfigresource.WithFormatOptions(format.WithStrict()))); err == nil {
+		t.Error("expected error for a format option that does not apply")
+	}
+}
@@ -2,32 +2,39 @@
 package file
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log/slog"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
-	"sync/atomic"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/soyacen/gonfig/format"
 	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
-var _ resource.Resource = (*Resource)(nil)
+var (
+	_ resource.MessageResource = (*Resource)(nil)
+	_ resource.ValueDecoder    = (*Resource)(nil)
+)
 
 // Resource represents a configuration resource loaded from a file
 type Resource struct {
+	// core parses file content and suppresses unchanged payloads
+	core *resource.Core
 	// filename is the path to the configuration file
 	filename string
-	// formatter is used for parsing file content into structured data
+	// filenames are the paths of the configuration file followed by its profile-specific variants
+	filenames []string
+	// formatter is used for parsing file content, it may report files it depends on
 	formatter format.Formatter
-	// pre is atomic storage for previous configuration data to detect changes
-	pre atomic.Value
+	// quietMissing stops reporting the disappearance of the file
+	quietMissing bool
 }
 
 // Load reads and parses the configuration file
@@ -39,16 +46,49 @@ type Resource struct {
 //   - *structpb.Struct: Parsed configuration data
 //   - error: Any error that occurred during loading or parsing
 func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
-	data, err := r.load(ctx)
-	if err != nil {
-		return nil, err
-	}
-	parsed, err := r.formatter.Parse(data)
-	if err != nil {
-		return nil, err
-	}
-	r.pre.Store(data)
-	return parsed, nil
+	return r.core.Load(ctx)
+}
+
+// LoadMessage retrieves the configuration and decodes it into a message
+// Formatters implementing format.MessageFormatter decode the data directly into the message
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during loading or decoding
+func (r *Resource) LoadMessage(ctx context.Context, message proto.Message) error {
+	return r.core.LoadMessage(ctx, message)
+}
+
+// DecodeMessage decodes the configuration of the latest load or change into a message
+// Parameters:
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (r *Resource) DecodeMessage(message proto.Message) error {
+	return r.core.DecodeMessage(message)
+}
+
+// DecodeValue decodes a configuration returned by Load or notified by Watch into a message,
+// from the data it was parsed from even if it has changed since
+// Parameters:
+//   - value: Configuration data returned by Load or notified by Watch
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (r *Resource) DecodeValue(value *structpb.Struct, message proto.Message) error {
+	return r.core.DecodeValue(value, message)
+}
+
+// RegisterMessage registers the type of the message the configuration is decoded into,
+// so that formats decoding data directly into messages work with Watch without a previous LoadMessage
+// Parameters:
+//   - message: Message of the type the configuration data is decoded into
+func (r *Resource) RegisterMessage(message proto.Message) {
+	r.core.RegisterMessage(message)
 }
 
 // load is an internal helper function to read raw file content
@@ -62,8 +102,26 @@ func (r *Resource) load(ctx context.Context) ([]byte, error) {
 	return os.ReadFile(r.filename)
 }
 
+// loadFile returns a fetch function reading the raw content of a profile-specific file
+// Parameters:
+//   - filename: Path to the profile-specific file
+//
+// Returns:
+//   - resource.FetchFunc: Function reading the file content
+func (r *Resource) loadFile(filename string) resource.FetchFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(filename)
+	}
+}
+
 // Watch monitors the file for changes and notifies subscribers when updates occur
-// It uses fsnotify to watch for filesystem events and filters for relevant changes
+// It uses fsnotify to watch for filesystem events and filters for relevant changes.
+// Symbolic links are followed, and replacing a link the file resolves through, such as the "..data"
+// link of a mounted Kubernetes ConfigMap, is a change.
+// Files replaced by renaming a temporary file over them, or removed and created again, as editors and
+// `sed -i` save them, are reloaded. If the file disappears, the last configuration is kept until the file
+// comes back, and the disappearance is reported unless resource.WithQuietMissing is set. Watched directories that are removed
+// or renamed, e.g. replaced by a deployment, are watched again once they are created again.
 // Parameters:
 //   - ctx: Context for cancellation
 //   - notifyFunc: Callback function for configuration updates
@@ -73,21 +131,10 @@ func (r *Resource) load(ctx context.Context) ([]byte, error) {
 //   - resource.StopFunc: Function to stop watching
 //   - error: Any immediate error during setup
 func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
-	// Validate notify function
-	if notifyFunc == nil {
-		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
-	}
-
-	// Set default error handler if none provided
-	if errFunc == nil {
-		errFunc = func(err error) {
-			slog.Error("gonfig: failed to watch file", slog.String("error", err.Error()))
-		}
-	}
-
-	// Check if context is already cancelled
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// Validate arguments and set default error handler
+	errFunc, err := r.core.PrepareWatch(ctx, notifyFunc, errFunc)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize filesystem watcher
@@ -97,18 +144,97 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 	}
 
 	// Watch the directory containing the file
+	dirs := map[string]bool{filepath.Dir(r.filename): true}
 	if err := fsWatcher.Add(filepath.Dir(r.filename)); err != nil {
 		return nil, err
 	}
 
-	// Create stop function with sync.Once to ensure it's only called once
-	stopC := make(chan struct{})
-	var onceStop sync.Once
-	stop := func(ctx context.Context) error {
-		onceStop.Do(func() { close(stopC) })
-		return nil
+	// Watch a directory, or its nearest existing ancestor if it does not exist,
+	// so that its creation is noticed
+	missing := make(map[string]bool)
+	watchDir := func(dir string) {
+		if dirs[dir] {
+			return
+		}
+		err := fsWatcher.Add(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing[dir] = true
+			for parent := filepath.Dir(dir); parent != dir && !dirs[parent]; dir, parent = parent, filepath.Dir(parent) {
+				if fsWatcher.Add(parent) == nil {
+					dirs[parent] = true
+					break
+				}
+			}
+			return
+		}
+		if err != nil {
+			errFunc(err)
+			return
+		}
+		dirs[dir] = true
 	}
 
+	// Watch the directories containing the files the formatter depends on, e.g. imported libraries,
+	// and the included files and directories matched by include patterns
+	watchDependencies := func() ([]string, []string) {
+		dependencies := r.dependencies()
+		includes, patterns := r.core.Includes()
+		dependencies = append(dependencies, includes...)
+		for _, dependency := range dependencies {
+			watchDir(filepath.Dir(dependency))
+		}
+		for _, pattern := range patterns {
+			if dir := filepath.Dir(pattern); !strings.ContainsAny(dir, "*?[") {
+				watchDir(dir)
+			}
+		}
+		return dependencies, patterns
+	}
+
+	// Watch the directories of the files, of the symbolic links the files resolve through and of their targets,
+	// so that e.g. the swap of the "..data" link of a mounted ConfigMap is noticed
+	watchSymlinks := func() ([]string, []string) {
+		var links, targets []string
+		for _, filename := range r.filenames {
+			watchDir(filepath.Dir(filename))
+			fileLinks, target := resolve(filename)
+			for _, link := range fileLinks {
+				watchDir(filepath.Dir(link))
+			}
+			watchDir(filepath.Dir(target))
+			links = append(links, fileLinks...)
+			targets = append(targets, target)
+		}
+		return links, targets
+	}
+	dependencies, patterns := watchDependencies()
+	links, targets := watchSymlinks()
+
+	// rewatch watches the directories of the files again, after they changed or a directory was removed or created
+	rewatch := func() {
+		clear(missing)
+		dependencies, patterns = watchDependencies()
+		links, targets = watchSymlinks()
+	}
+
+	// reload reloads the files, unless the file has disappeared: its disappearance is reported once
+	// and the last configuration is kept until it comes back
+	disappeared := false
+	reload := func() {
+		if _, err := os.Stat(r.filename); errors.Is(err, fs.ErrNotExist) {
+			if !disappeared && !r.quietMissing {
+				errFunc(fmt.Errorf("gonfig: file %s disappeared, keeping the last configuration: %w", r.filename, resource.ErrNotExist))
+			}
+			disappeared = true
+			return
+		}
+		disappeared = false
+		r.core.Reload(ctx, notifyFunc, errFunc)
+	}
+
+	// Create stop function
+	stop, stopC := resource.NewStopFunc()
+
 	// Start watching in a separate goroutine
 	go func() {
 		// Ensure watcher is closed when goroutine exits
@@ -142,36 +268,45 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 				if !ok {
 					return
 				}
-				// Only process events for our specific file
-				if filepath.Clean(event.Name) != filepath.Clean(r.filename) {
-					continue
-				}
-				// Only react to write/create events
-				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				// Only process events for our specific files
+				name := filepath.Clean(event.Name)
+				written := event.Has(fsnotify.Write) || event.Has(fsnotify.Create)
+				removed := event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+				switch {
+				case (written || removed) && (slices.Contains(r.filenames, name) || slices.Contains(targets, name)):
+					// Handle file change, including a temporary file renamed over the file,
+					// or the file renamed away or removed before an editor writes it again
+					reload()
+				case slices.Contains(links, name):
+					// A symbolic link was created, replaced, renamed or removed, e.g. the "..data" link swapped
+					// by the atomic writer of the kubelet, so the files may now resolve to other targets
+					reload()
+				case written && slices.Contains(dependencies, name):
+					// A dependency changed, the unchanged files must be parsed again
+					r.core.Refresh(ctx, notifyFunc, errFunc)
+				case slices.ContainsFunc(patterns, func(pattern string) bool { return resource.MatchPattern(pattern, name) }):
+					// A file matching an include pattern was added, changed or removed
+					r.core.Refresh(ctx, notifyFunc, errFunc)
+				case removed && dirs[name]:
+					// A watched directory was removed or renamed, together with its watch; renaming it reports
+					// no events for the files it contains. It is watched again, or its nearest existing ancestor,
+					// before reloading, so that a replacement is not missed
+					delete(dirs, name)
+					_ = fsWatcher.Remove(name)
+					rewatch()
+					reload()
 					continue
-				}
-				// Handle file change
-				data, err := r.load(ctx)
-				if err != nil {
-					errFunc(err)
+				case event.Has(fsnotify.Create) && created(missing, name):
+					// A missing directory was created, its files may have been written before it is watched,
+					// so it is watched again before reloading
+					rewatch()
+					reload()
 					continue
-				}
-				// Compare with previous data to avoid unnecessary notifications
-				preData := r.pre.Load()
-				if preData != nil && bytes.Equal(preData.([]byte), data) {
-					continue // Skip if content hasn't changed
-				}
-				// Parse new configuration data
-				newValue, err := r.formatter.Parse(data)
-				if err != nil {
-					errFunc(err)
+				default:
 					continue
 				}
-				// Notify subscribers of the change
-				notifyFunc(newValue)
-				// Store new data for future comparisons
-				r.pre.Store(data)
-
+				// The changed files may depend on or include other files, or resolve to other targets
+				rewatch()
 			}
 		}
 	}()
@@ -179,30 +314,74 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 	return stop, nil
 }
 
+// created reports whether a created path is a missing directory or one of its ancestors
+func created(missing map[string]bool, name string) bool {
+	for dir := range missing {
+		if dir == name || strings.HasPrefix(dir, name+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencies returns the paths of the files the formatter read while parsing
+func (r *Resource) dependencies() []string {
+	formatter, ok := r.formatter.(format.DependentFormatter)
+	if !ok {
+		return nil
+	}
+	var dependencies []string
+	for _, dependency := range formatter.Dependencies() {
+		dependencies = append(dependencies, filepath.Clean(dependency))
+	}
+	return dependencies
+}
+
 // New creates a new file-based configuration resource
-// It validates the file extension and finds an appropriate formatter
+// It finds the formatter of the format set by resource.WithFormat or of the file extension;
+// without either, the format is detected from the content.
+// For every active profile, the profile-specific file (e.g. "config.prod.yaml" for "config.yaml")
+// is deep-merged on top of the configuration file if it exists.
+// A format.SourceFormatter is bound to the file, so that e.g. relative imports resolve against its directory.
+// Include directives (see resource.IncludeKey) resolve against the directory of the including file,
+// and the included files are watched as well.
 // Parameters:
 //   - filename: Path to the configuration file
+//   - opts: Options such as resource.WithProfiles, resource.WithFormat and resource.WithRegistry
 //
 // Returns:
 //   - *Resource: New file resource instance
 //   - error: Any error during initialization
-func New(filename string) (*Resource, error) {
-	// Extract file extension
-	ext := strings.TrimPrefix(filepath.Ext(filename), ".")
-	if ext == "" {
-		return nil, fmt.Errorf("config: file extension is empty")
+func New(filename string, opts ...resource.Option) (*Resource, error) {
+	options := resource.NewOptions(opts...)
+
+	// Find appropriate formatter for the explicit format or the file extension, or sniff the format
+	formatter, err := options.Formatter(filename)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find appropriate formatter for the file extension
-	formatter, ok := format.GetFormatter(ext)
-	if !ok {
-		return nil, fmt.Errorf("config: not found formatter for %s", ext)
+	// Bind the formatter to the file
+	if sourceFormatter, ok := formatter.(format.SourceFormatter); ok {
+		formatter = sourceFormatter.WithSource(filename)
 	}
 
 	// Return new resource instance
-	return &Resource{
-		filename:  filename,
-		formatter: formatter,
-	}, nil
+	r := &Resource{
+		filename:     filename,
+		filenames:    []string{filepath.Clean(filename)},
+		formatter:    formatter,
+		quietMissing: options.QuietMissing,
+	}
+	r.core = resource.NewCore("file", r.load, formatter)
+	for _, profile := range options.Profiles {
+		profileFilename := filepath.Clean(options.ProfileName(filename, profile))
+		r.filenames = append(r.filenames, profileFilename)
+		r.core.AddOverlay(r.loadFile(profileFilename))
+	}
+	r.core.EnableIncludes(resource.FileIncluder{}, options, r.filenames...)
+	for _, transformer := range options.Transformers {
+		r.core.AddTransformer(transformer)
+	}
+	return r, nil
 }
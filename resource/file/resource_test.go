@@ -1,17 +1,27 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/soyacen/gonfig"
+	"github.com/soyacen/gonfig/format"
+	_ "github.com/soyacen/gonfig/format/binpb"
 	_ "github.com/soyacen/gonfig/format/json"
 	_ "github.com/soyacen/gonfig/format/yaml"
+	gonfigresource "github.com/soyacen/gonfig/resource"
 
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -23,7 +33,7 @@ func TestNew(t *testing.T) {
 	}{
 		{"Valid YAML File", "test.yaml", ""},
 		{"Valid JSON File", "test.json", ""},
-		{"Empty Extension", "test", "config: file extension is empty"},
+		{"Empty Extension", "test", ""},
 		{"Unsupported Extension", "test.txt", "config: not found formatter for txt"},
 	}
 
@@ -145,3 +155,855 @@ key:
 		t.Errorf("expected value 'updated_value'; got %q", value)
 	}
 }
+
+func TestLoad_Profiles(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.yaml")
+	files := map[string]string{
+		"config.yaml":      "server:\n  addr: 0.0.0.0\n  port: 8080\nname: base\n",
+		"config.prod.yaml": "server:\n  port: 80\n",
+		"config.eu.yaml":   "name: eu\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resource, err := New(testFile, gonfigresource.WithProfiles("prod", "eu", "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	structData, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"server": map[string]any{
+			"addr": "0.0.0.0",
+			"port": float64(80),
+		},
+		"name": "eu",
+	}
+	if !reflect.DeepEqual(structData.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, structData.AsMap())
+	}
+}
+
+func TestLoad_ProfileSeparator(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "app.yaml")
+	files := map[string]string{
+		"app.yaml":      "name: base\n",
+		"app.prod.yaml": "name: dot\n",
+		"app-prod.yaml": "name: prod\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resource, err := New(testFile, gonfigresource.WithProfiles("prod"), gonfigresource.WithProfileSeparator("-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	structData, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := structData.GetFields()["name"].GetStringValue(); name != "prod" {
+		t.Errorf("expected name 'prod'; got %q", name)
+	}
+}
+
+func TestLoad_ProfilesEnv(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.json")
+	if err := os.WriteFile(testFile, []byte(`{"name":"base"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "config.dev.json"), []byte(`{"name":"dev"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(gonfigresource.ProfilesEnv, "dev")
+
+	resource, err := New(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structData, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := structData.GetFields()["name"].GetStringValue(); name != "dev" {
+		t.Errorf("expected name 'dev'; got %q", name)
+	}
+}
+
+func TestWatch_Profiles(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.yaml")
+	profileFile := filepath.Join(tempDir, "config.prod.yaml")
+	if err := os.WriteFile(testFile, []byte("name: base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile, gonfigresource.WithProfiles("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct)
+	stop, err := resource.Watch(ctx, func(value *structpb.Struct) { c <- value }, func(err error) { t.Errorf("Error: %v", err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	if err := os.WriteFile(profileFile, []byte("name: prod\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-c:
+		if name := value.GetFields()["name"].GetStringValue(); name != "prod" {
+			t.Errorf("expected name 'prod'; got %q", name)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for profile change")
+	}
+}
+
+// importFormatter parses data naming a file relative to the source, whose content becomes the "name" field
+type importFormatter struct {
+	dir          string
+	mutex        sync.Mutex
+	dependencies []string
+}
+
+func (f *importFormatter) WithSource(name string) format.Formatter {
+	return &importFormatter{dir: filepath.Dir(name)}
+}
+
+func (f *importFormatter) Parse(data []byte) (*structpb.Struct, error) {
+	filename := filepath.Join(f.dir, strings.TrimSpace(string(data)))
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	f.dependencies = append(f.dependencies, filename)
+	f.mutex.Unlock()
+	return structpb.NewStruct(map[string]any{"name": strings.TrimSpace(string(content))})
+}
+
+func (f *importFormatter) Dependencies() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.dependencies...)
+}
+
+func TestWatch_Dependencies(t *testing.T) {
+	format.RegisterFormatter("import", &importFormatter{})
+	tempDir := t.TempDir()
+	libDir := filepath.Join(tempDir, "lib")
+	if err := os.Mkdir(libDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	testFile := filepath.Join(tempDir, "config.import")
+	libFile := filepath.Join(libDir, "name.txt")
+	if err := os.WriteFile(testFile, []byte("lib/name.txt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(libFile, []byte("before"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := value.GetFields()["name"].GetStringValue(); name != "before" {
+		t.Errorf("expected name 'before'; got %q", name)
+	}
+
+	c := make(chan *structpb.Struct)
+	stop, err := resource.Watch(ctx, func(value *structpb.Struct) { c <- value }, func(err error) { t.Errorf("Error: %v", err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	if err := os.WriteFile(libFile, []byte("after"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The write may be observed in several steps, wait for the final content
+	for {
+		select {
+		case value := <-c:
+			if name := value.GetFields()["name"].GetStringValue(); name == "after" {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for dependency change")
+		}
+	}
+}
+
+func TestWatch_Include(t *testing.T) {
+	tempDir := t.TempDir()
+	confDir := filepath.Join(tempDir, "conf.d")
+	if err := os.Mkdir(confDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	testFile := filepath.Join(tempDir, "config.yaml")
+	commonFile := filepath.Join(tempDir, "common.yaml")
+	files := map[string]string{
+		testFile:                          "$include: [common.yaml, conf.d/*.yaml]\nname: app\n",
+		commonFile:                        "name: common\nlevel: info\n",
+		filepath.Join(confDir, "db.yaml"): "db: primary\n",
+	}
+	for filename, content := range files {
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"name": "app", "level": "info", "db": "primary"}
+	if !reflect.DeepEqual(expected, value.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, value.AsMap())
+	}
+
+	c := make(chan *structpb.Struct)
+	stop, err := resource.Watch(ctx, func(value *structpb.Struct) { c <- value }, func(err error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// The writes may be observed in several steps, wait for the final content
+	waitFor := func(key string, expected string) {
+		for {
+			select {
+			case value := <-c:
+				if value.GetFields()[key].GetStringValue() == expected {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s %q", key, expected)
+			}
+		}
+	}
+	if err := os.WriteFile(commonFile, []byte("name: common\nlevel: debug\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("level", "debug")
+	if err := os.WriteFile(filepath.Join(confDir, "cache.yaml"), []byte("cache: redis\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("cache", "redis")
+}
+
+func TestLoad_Format(t *testing.T) {
+	tempDir := t.TempDir()
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		opts     []gonfigresource.Option
+	}{
+		{"Sniff JSON", "config", `{"key": "value"}`, nil},
+		{"Sniff YAML", "config", "key: value\n", nil},
+		{"Explicit Format", "config.conf", "key: value\n", []gonfigresource.Option{gonfigresource.WithFormat("yaml")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tempDir, tt.filename)
+			if err := os.WriteFile(testFile, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			resource, err := New(testFile, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := resource.Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := value.GetFields()["key"].GetStringValue(); got != "value" {
+				t.Errorf("expected 'value'; got %q", got)
+			}
+		})
+	}
+}
+
+func TestWatch_Template(t *testing.T) {
+	t.Setenv("GONFIG_TEST_REDIS_HOST", "redis.local")
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(testFile, []byte(`addr: {{ env "GONFIG_TEST_REDIS_HOST" }}:6379`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile, gonfigresource.WithTemplate(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["addr"].GetStringValue(); got != "redis.local:6379" {
+		t.Fatalf("expected 'redis.local:6379'; got %q", got)
+	}
+
+	// Load renders the unchanged data again
+	t.Setenv("GONFIG_TEST_REDIS_HOST", "redis.remote")
+	value, err = resource.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["addr"].GetStringValue(); got != "redis.remote:6379" {
+		t.Fatalf("expected 'redis.remote:6379'; got %q", got)
+	}
+
+	c := make(chan *structpb.Struct, 1)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		if newValue.GetFields()["addr"].GetStringValue() == "" {
+			return
+		}
+		select {
+		case c <- newValue:
+		default:
+		}
+	}, func(err error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	if err := os.WriteFile(testFile, []byte(`addr: {{ env "GONFIG_TEST_REDIS_HOST" }}:6380`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case newValue := <-c:
+		if got := newValue.GetFields()["addr"].GetStringValue(); got != "redis.remote:6380" {
+			t.Errorf("expected 'redis.remote:6380'; got %q", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the rendered change")
+	}
+}
+
+func TestLoad_Compressed(t *testing.T) {
+	tempDir := t.TempDir()
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte("key: value\nport: 8080\n"))
+	_ = w.Close()
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+	}{
+		{"Double Extension", "config.yaml.gz", gz.Bytes()},
+		{"Magic Bytes", "config.yaml", gz.Bytes()},
+		{"Sniffed", "config", gz.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tempDir, tt.filename)
+			if err := os.WriteFile(testFile, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			resource, err := New(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := resource.Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := value.GetFields()["key"].GetStringValue(); got != "value" {
+				t.Errorf("expected 'value'; got %q", got)
+			}
+		})
+	}
+
+	// Profiles keep the compression extension last
+	if err := os.WriteFile(filepath.Join(tempDir, "config.prod.yaml.gz"), []byte("port: 9090\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resource, err := New(filepath.Join(tempDir, "config.yaml.gz"), gonfigresource.WithProfiles("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "not gz-compressed") {
+		t.Errorf("expected error for uncompressed data of a .gz file; got %v", err)
+	}
+}
+
+func TestLoadMessage_Errors(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.yaml")
+	profileFile := filepath.Join(tempDir, "config.prod.yaml")
+	if err := os.WriteFile(testFile, []byte("name: port\nnumber: 8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(profileFile, []byte("# production\nnumber: abc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resource, err := New(testFile, gonfigresource.WithProfiles("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = resource.LoadMessage(context.Background(), &descriptorpb.FieldDescriptorProto{})
+	var convertErr *format.ConvertError
+	if !errors.As(err, &convertErr) {
+		t.Fatalf("expected *format.ConvertError; got %v", err)
+	}
+	expected := format.ConvertError{Source: profileFile, Position: format.Position{Line: 2, Column: 1}, Path: "number", Err: convertErr.Err}
+	if *convertErr != expected {
+		t.Errorf("expected %v; got %v", &expected, convertErr)
+	}
+
+	if err := os.WriteFile(profileFile, []byte("number: 1\nname: : port\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = resource.Load(context.Background())
+	var parseErr *format.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *format.ParseError; got %v", err)
+	}
+	if parseErr.Source != profileFile || parseErr.Line != 2 {
+		t.Errorf("expected an error at line 2 of %s; got %v", profileFile, err)
+	}
+}
+
+// writeConfigMap writes files the way the atomic writer of the kubelet updates a mounted ConfigMap:
+// into a new timestamped directory, then renaming a "..data_tmp" link to it over "..data",
+// linking every key to "..data" and removing the previous directory
+func writeConfigMap(t *testing.T, dir string, version string, files map[string]string) {
+	t.Helper()
+	versionDir := filepath.Join(dir, "..2026_10_18_"+version)
+	if err := os.Mkdir(versionDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(versionDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldVersionDir, _ := os.Readlink(filepath.Join(dir, "..data"))
+	if err := os.Symlink(filepath.Base(versionDir), filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join("..data", name), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if oldVersionDir != "" {
+		if err := os.RemoveAll(filepath.Join(dir, oldVersionDir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tempDir := t.TempDir()
+	writeConfigMap(t, tempDir, "1", map[string]string{"config.yaml": "key: v1"})
+
+	testFile := filepath.Join(tempDir, "config.yaml")
+	links, target := resolve(testFile)
+	expectedLinks := []string{testFile, filepath.Join(tempDir, "..data")}
+	if !reflect.DeepEqual(expectedLinks, links) {
+		t.Errorf("expected links %v; got %v", expectedLinks, links)
+	}
+	if expected := filepath.Join(tempDir, "..2026_10_18_1", "config.yaml"); target != expected {
+		t.Errorf("expected target %q; got %q", expected, target)
+	}
+
+	missing := filepath.Join(tempDir, "missing", "config.yaml")
+	if links, target := resolve(missing); len(links) != 0 || target != missing {
+		t.Errorf("expected %q unresolved; got %v and %q", missing, links, target)
+	}
+}
+
+func TestWatch_ConfigMap(t *testing.T) {
+	tempDir := t.TempDir()
+	writeConfigMap(t, tempDir, "1", map[string]string{"config.yaml": "key: v1"})
+	testFile := filepath.Join(tempDir, "config.yaml")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		c <- newValue
+	}, func(err error) {
+		// The old version may be read while its directory is removed
+		t.Logf("Error: %v", err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// The link of the key is unchanged, only "..data" is swapped; the second swap
+	// checks that the watcher follows the link to the new version
+	for _, version := range []string{"2", "3"} {
+		writeConfigMap(t, tempDir, version, map[string]string{"config.yaml": "key: v" + version})
+		for done := false; !done; {
+			select {
+			case newValue := <-c:
+				done = newValue.GetFields()["key"].GetStringValue() == "v"+version
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for version %s", version)
+			}
+		}
+	}
+}
+
+func TestWatch_Symlink(t *testing.T) {
+	targetDir, linkDir := t.TempDir(), t.TempDir()
+	targetFile := filepath.Join(targetDir, "shared.yaml")
+	if err := os.WriteFile(targetFile, []byte("key: v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testFile := filepath.Join(linkDir, "config.yaml")
+	if err := os.Symlink(targetFile, testFile); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		c <- newValue
+	}, func(err error) {
+		t.Logf("Error: %v", err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// The target is written in place, in a directory other than the one of the link
+	if err := os.WriteFile(targetFile, []byte("key: v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for done := false; !done; {
+		select {
+		case newValue := <-c:
+			done = newValue.GetFields()["key"].GetStringValue() == "v2"
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the change of the target")
+		}
+	}
+}
+
+// waitKey waits for a notified configuration whose key has the expected value
+func waitKey(t *testing.T, ctx context.Context, c <-chan *structpb.Struct, expected string) {
+	t.Helper()
+	for {
+		select {
+		case newValue := <-c:
+			if newValue.GetFields()["key"].GetStringValue() == expected {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+}
+
+func TestWatch_EditorSave(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(testFile, []byte("key: v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile, gonfigresource.WithQuietMissing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		c <- newValue
+	}, func(err error) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Error: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// A temporary file renamed over the file, like `sed -i` and JetBrains IDEs
+	tempFile := filepath.Join(tempDir, ".config.yaml.tmp")
+	if err := os.WriteFile(tempFile, []byte("key: v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tempFile, testFile); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v2")
+
+	// The file renamed to a backup and written again, like Vim
+	if err := os.Rename(testFile, testFile+"~"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testFile, []byte("key: v3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v3")
+
+	// The file removed and created again
+	if err := os.Remove(testFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testFile, []byte("key: v4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v4")
+}
+
+func TestWatch_Disappeared(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(testFile, []byte("key: v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	errC := make(chan error, 16)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		c <- newValue
+	}, func(err error) {
+		errC <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	if err := os.Remove(testFile); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errC:
+		if !errors.Is(err, gonfigresource.ErrNotExist) || !strings.Contains(err.Error(), "disappeared") {
+			t.Errorf("expected the disappearance to be reported; got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the disappearance")
+	}
+
+	// The file comes back
+	if err := os.WriteFile(testFile, []byte("key: v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v2")
+}
+
+func TestWatch_DirRecreated(t *testing.T) {
+	tempDir := t.TempDir()
+	confDir := filepath.Join(tempDir, "conf")
+	if err := os.Mkdir(confDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	testFile := filepath.Join(confDir, "config.yaml")
+	if err := os.WriteFile(testFile, []byte("key: v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(testFile, gonfigresource.WithQuietMissing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	stop, err := resource.Watch(ctx, func(newValue *structpb.Struct) {
+		c <- newValue
+	}, func(err error) {
+		t.Logf("Error: %v", err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// The directory removed and created again
+	if err := os.RemoveAll(confDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(confDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testFile, []byte("key: v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v2")
+
+	// The directory replaced by renaming another one with the file already written, like a deployment
+	newDir := filepath.Join(tempDir, "conf.new")
+	if err := os.Mkdir(newDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(newDir, "config.yaml"), []byte("key: v3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(confDir, filepath.Join(tempDir, "conf.old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(newDir, confDir); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v3")
+
+	// The file of the new directory is watched
+	if err := os.WriteFile(testFile, []byte("key: v4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitKey(t, ctx, c, "v4")
+}
+
+func TestWatch_Message(t *testing.T) {
+	marshal := func(value int64) []byte {
+		data, err := proto.Marshal(&descriptorpb.UninterpretedOption{NegativeIntValue: proto.Int64(value)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	tests := []struct {
+		name string
+		wrap func(gonfigresource.Resource) gonfigresource.Resource
+	}{
+		{"Resource", func(r gonfigresource.Resource) gonfigresource.Resource { return r }},
+		{"Wrapped", func(r gonfigresource.Resource) gonfigresource.Resource {
+			return gonfigresource.Wrap(r, gonfigresource.Logging(nil), gonfigresource.Dedup())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "config.binpb")
+			if err := os.WriteFile(testFile, marshal(-1), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			fileResource, err := New(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resource := tt.wrap(fileResource)
+			if _, ok := resource.(gonfigresource.MessageResource); !ok {
+				t.Fatalf("expected %T to implement MessageResource", resource)
+			}
+
+			// Watch without a previous Load
+			c := make(chan *descriptorpb.UninterpretedOption, 16)
+			stop, err := gonfig.Watch(ctx, resource, func(conf *descriptorpb.UninterpretedOption) {
+				c <- conf
+			}, func(err error) {
+				t.Logf("Error: %v", err)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stop(ctx)
+
+			if err := os.WriteFile(testFile, marshal(-(1<<60 + 1)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for done := false; !done; {
+				select {
+				case conf := <-c:
+					done = conf.GetNegativeIntValue() == -(1<<60 + 1)
+				case <-ctx.Done():
+					t.Fatal("timed out waiting for the change")
+				}
+			}
+
+			// Load after Watch
+			conf, err := gonfig.Load[*descriptorpb.UninterpretedOption](ctx, resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conf.GetNegativeIntValue() != -(1<<60 + 1) {
+				t.Errorf("expected %d; got %d", -(1<<60 + 1), conf.GetNegativeIntValue())
+			}
+		})
+	}
+}
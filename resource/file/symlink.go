@@ -0,0 +1,61 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// maxSymlinks limits the symbolic links followed while resolving a path, like the limit of the kernel
+const maxSymlinks = 40
+
+// resolve follows the symbolic links of a path, including those of its parent directories.
+// Mounted Kubernetes ConfigMaps link every key to "..data/<key>", where "..data" links to a timestamped
+// directory swapped atomically on updates, so the links must be watched as well as the target.
+// Parameters:
+//   - filename: Path to resolve
+//
+// Returns:
+//   - []string: Symbolic links traversed, in order, e.g. "config.yaml" and "..data"
+//   - string: Path of the target, the unresolved remainder if a part of the path does not exist
+func resolve(filename string) ([]string, string) {
+	var links []string
+	name := filepath.Clean(filename)
+	for len(links) < maxSymlinks {
+		link, target, ok := firstSymlink(name)
+		if !ok {
+			break
+		}
+		links = append(links, link)
+		name = target
+	}
+	return links, name
+}
+
+// firstSymlink finds the first symbolic link among the prefixes of a path and replaces it by its target
+func firstSymlink(name string) (string, string, bool) {
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && name[i] != os.PathSeparator {
+			continue
+		}
+		prefix := name[:i]
+		if prefix == "" {
+			continue
+		}
+		info, err := os.Lstat(prefix)
+		if err != nil {
+			return "", "", false
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := os.Readlink(prefix)
+		if err != nil {
+			return "", "", false
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(prefix), target)
+		}
+		return prefix, filepath.Join(target, name[i:]), true
+	}
+	return "", "", false
+}
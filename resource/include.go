@@ -0,0 +1,284 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// IncludeKey is the key of the include directive.
+// Its value is the name of a source or a list of names, resolved relative to the including source;
+// names may be glob patterns such as "db/*.yaml". The included sources are deep-merged in order,
+// and the object containing the directive is merged on top of them, so it can override included values.
+// The directive may appear in nested objects, e.g. {"database": {"$include": "db.yaml"}}.
+const IncludeKey = "$include"
+
+// Includer locates and reads the sources named by include directives,
+// e.g. files relative to the including file, or keys of the same KV store.
+type Includer interface {
+	// Join resolves the name of an included source relative to the including source
+	// Parameters:
+	//   - from: Name of the including source
+	//   - name: Name or pattern given in the include directive
+	//
+	// Returns:
+	//   - string: Name or pattern of the included source
+	Join(from string, name string) string
+	// Glob returns the names of the sources matching a pattern in lexical order
+	// Parameters:
+	//   - ctx: Context for cancellation and timeouts
+	//   - pattern: Pattern returned by Join
+	//
+	// Returns:
+	//   - []string: Names of the matching sources
+	//   - error: Any error listing the sources
+	Glob(ctx context.Context, pattern string) ([]string, error)
+	// Fetch retrieves the raw data of a source
+	// Parameters:
+	//   - ctx: Context for cancellation and timeouts
+	//   - name: Name of the source
+	//
+	// Returns:
+	//   - []byte: Raw configuration data
+	//   - error: Any error that occurred while fetching the data
+	Fetch(ctx context.Context, name string) ([]byte, error)
+}
+
+// FileIncluder resolves include directives against the file system,
+// names are paths relative to the directory of the including file.
+type FileIncluder struct{}
+
+// Join resolves an included path relative to the directory of the including file
+func (FileIncluder) Join(from string, name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(filepath.Dir(from), name)
+}
+
+// Glob returns the paths of the files matching a pattern
+func (FileIncluder) Glob(_ context.Context, pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// Fetch reads an included file
+func (FileIncluder) Fetch(_ context.Context, name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// MatchPattern reports whether a file path matches an include pattern returned by Core.Includes,
+// a malformed pattern matches nothing
+// Parameters:
+//   - pattern: Pattern of file paths, e.g. "conf/db/*.yaml"
+//   - name: Path of a file
+//
+// Returns:
+//   - bool: Whether the path matches
+func MatchPattern(pattern string, name string) bool {
+	matched, _ := filepath.Match(pattern, name)
+	return matched
+}
+
+// EnableIncludes resolves include directives in the parsed data of the layers.
+// Included sources are parsed with the formatter registered for their extension,
+// otherwise with the formatter the options give for their name.
+// Include directives are not resolved in data decoded directly into messages by a format.MessageFormatter.
+// Parameters:
+//   - includer: Includer of the resource
+//   - options: Options providing the formatters of included sources
+//   - names: Names of the base source followed by its overlays, directives resolve relative to them
+func (c *Core) EnableIncludes(includer Includer, options *Options, names ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.includer = includer
+	c.options = options
+	for i, name := range names {
+		if i < len(c.layers) {
+			c.layers[i].name = name
+		}
+	}
+}
+
+// Includes returns the sources included by the data of the latest load or notification,
+// and the glob patterns they were matched by, so that resources can watch them and call Refresh on changes
+// Returns:
+//   - []string: Names of the included sources
+//   - []string: Glob patterns of include directives
+func (c *Core) Includes() ([]string, []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var names, patterns []string
+	for _, l := range c.layers {
+		names, patterns = mergeNames(names, l.includes), mergeNames(patterns, l.patterns)
+	}
+	return names, patterns
+}
+
+// includeState collects the sources read while resolving the include directives of a layer
+type includeState struct {
+	ctx      context.Context
+	includes []string
+	patterns []string
+}
+
+// include resolves the include directives of the value parsed from a source,
+// stack holds the names of the including sources to detect cycles
+func (c *Core) include(state *includeState, stack []string, value *structpb.Struct) (*structpb.Struct, error) {
+	directive, ok := value.GetFields()[IncludeKey]
+	fields := make(map[string]*structpb.Value, len(value.GetFields()))
+	for key, field := range value.GetFields() {
+		if key == IncludeKey {
+			continue
+		}
+		field, err := c.includeValue(state, stack, field)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = field
+	}
+	value = &structpb.Struct{Fields: fields}
+	if !ok {
+		return value, nil
+	}
+
+	from := stack[len(stack)-1]
+	names, err := includeNames(from, directive)
+	if err != nil {
+		return nil, err
+	}
+	var values []*structpb.Struct
+	for _, name := range names {
+		sources, err := c.resolveInclude(state, from, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			included, err := c.includeSource(state, stack, source)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, included)
+		}
+	}
+	return Merge(append(values, value)...), nil
+}
+
+// includeValue resolves the include directives of the objects nested in a value
+func (c *Core) includeValue(state *includeState, stack []string, value *structpb.Value) (*structpb.Value, error) {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StructValue:
+		s, err := c.include(state, stack, kind.StructValue)
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewStructValue(s), nil
+	case *structpb.Value_ListValue:
+		values := make([]*structpb.Value, len(kind.ListValue.GetValues()))
+		for i, item := range kind.ListValue.GetValues() {
+			item, err := c.includeValue(state, stack, item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = item
+		}
+		return structpb.NewListValue(&structpb.ListValue{Values: values}), nil
+	default:
+		return value, nil
+	}
+}
+
+// includeNames returns the names given by an include directive
+func includeNames(from string, directive *structpb.Value) ([]string, error) {
+	switch kind := directive.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return []string{kind.StringValue}, nil
+	case *structpb.Value_ListValue:
+		names := make([]string, 0, len(kind.ListValue.GetValues()))
+		for _, item := range kind.ListValue.GetValues() {
+			name, ok := item.GetKind().(*structpb.Value_StringValue)
+			if !ok {
+				return nil, fmt.Errorf("gonfig: %s: %s must be a name or a list of names", from, IncludeKey)
+			}
+			names = append(names, name.StringValue)
+		}
+		return names, nil
+	default:
+		return nil, fmt.Errorf("gonfig: %s: %s must be a name or a list of names", from, IncludeKey)
+	}
+}
+
+// resolveInclude returns the names of the sources matching a name of an include directive
+func (c *Core) resolveInclude(state *includeState, from string, name string) ([]string, error) {
+	name = c.includer.Join(from, name)
+	if !strings.ContainsAny(name, "*?[") {
+		return []string{name}, nil
+	}
+	state.patterns = mergeNames(state.patterns, []string{name})
+	names, err := c.includer.Glob(state.ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("gonfig: %s: %w", from, err)
+	}
+	return names, nil
+}
+
+// includeSource fetches, parses and resolves the include directives of an included source
+func (c *Core) includeSource(state *includeState, stack []string, name string) (*structpb.Struct, error) {
+	if slices.Contains(stack, name) {
+		return nil, fmt.Errorf("gonfig: include cycle: %s -> %s", strings.Join(stack, " -> "), name)
+	}
+	state.includes = mergeNames(state.includes, []string{name})
+	data, err := c.includer.Fetch(state.ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("gonfig: %s: %w", stack[len(stack)-1], err)
+	}
+	formatter, err := c.includeFormatter(name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := formatter.Parse(data)
+	if err != nil {
+		return nil, parseError(name, err)
+	}
+	return c.include(state, append(slices.Clip(stack), name), value)
+}
+
+// includeFormatter returns the formatter of an included source, formatters are kept across reloads
+func (c *Core) includeFormatter(name string) (format.Formatter, error) {
+	if formatter, ok := c.includeFormatters[name]; ok {
+		return formatter, nil
+	}
+	formatter, ok := c.options.FormatRegistry().Get(c.options.Ext(name))
+	var err error
+	if ok {
+		formatter, err = c.options.WrapFormatter(formatter, name)
+	} else {
+		formatter, err = c.options.Formatter(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sourceFormatter, ok := formatter.(format.SourceFormatter); ok {
+		formatter = sourceFormatter.WithSource(name)
+	}
+	if c.includeFormatters == nil {
+		c.includeFormatters = make(map[string]format.Formatter)
+	}
+	c.includeFormatters[name] = formatter
+	return formatter, nil
+}
+
+// mergeNames appends the names missing from names
+func mergeNames(names []string, more []string) []string {
+	for _, name := range more {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,166 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/soyacen/gonfig/format/json"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// mapIncluder includes sources stored in a map
+type mapIncluder map[string]string
+
+func (m mapIncluder) Join(from string, name string) string {
+	return path.Join(path.Dir(from), name)
+}
+
+func (m mapIncluder) Glob(_ context.Context, pattern string) ([]string, error) {
+	var names []string
+	for name := range m {
+		if matched, _ := path.Match(pattern, name); matched {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (m mapIncluder) Fetch(_ context.Context, name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+// includeCore creates a core loading the source "app/config.json" from the includer
+func includeCore(includer mapIncluder) *Core {
+	core := NewCore("test", func(ctx context.Context) ([]byte, error) {
+		return includer.Fetch(ctx, "app/config.json")
+	}, json.Json{})
+	core.EnableIncludes(includer, NewOptions(), "app/config.json")
+	return core
+}
+
+func TestCore_Include(t *testing.T) {
+	core := includeCore(mapIncluder{
+		"app/config.json":       `{"$include": ["common.json", "db/*.json"], "name": "api", "log": {"level": "debug"}}`,
+		"app/common.json":       `{"name": "common", "log": {"level": "info", "format": "json"}}`,
+		"app/db/1-primary.json": `{"db": {"primary": {"$include": "../../shared/dsn.json"}}}`,
+		"app/db/2-replica.json": `{"db": {"replica": {"host": "replica"}}}`,
+		"shared/dsn.json":       `{"host": "primary", "port": 5432}`,
+	})
+	value, err := core.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"name": "api",
+		"log":  map[string]any{"level": "debug", "format": "json"},
+		"db": map[string]any{
+			"primary": map[string]any{"host": "primary", "port": float64(5432)},
+			"replica": map[string]any{"host": "replica"},
+		},
+	}
+	if !reflect.DeepEqual(expected, value.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, value.AsMap())
+	}
+
+	includes, patterns := core.Includes()
+	expectedIncludes := []string{"app/common.json", "app/db/1-primary.json", "shared/dsn.json", "app/db/2-replica.json"}
+	if !reflect.DeepEqual(expectedIncludes, includes) {
+		t.Errorf("expected includes %v; got %v", expectedIncludes, includes)
+	}
+	if !reflect.DeepEqual([]string{"app/db/*.json"}, patterns) {
+		t.Errorf("expected patterns [app/db/*.json]; got %v", patterns)
+	}
+}
+
+func TestCore_Include_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		includer mapIncluder
+		err      string
+	}{
+		{"Cycle", mapIncluder{
+			"app/config.json": `{"$include": "a.json"}`,
+			"app/a.json":      `{"$include": "b.json"}`,
+			"app/b.json":      `{"$include": "config.json"}`,
+		}, "include cycle: app/config.json -> app/a.json -> app/b.json -> app/config.json"},
+		{"Missing", mapIncluder{
+			"app/config.json": `{"$include": "missing.json"}`,
+		}, "file does not exist"},
+		{"Invalid Directive", mapIncluder{
+			"app/config.json": `{"$include": 1}`,
+		}, "must be a name or a list of names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := includeCore(tt.includer).Load(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q; got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCore_Refresh(t *testing.T) {
+	includer := mapIncluder{
+		"app/config.json": `{"$include": "common.json"}`,
+		"app/common.json": `{"name": "before"}`,
+	}
+	core := includeCore(includer)
+	if _, err := core.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var notified []string
+	notifyFunc := func(value *structpb.Struct) {
+		notified = append(notified, value.GetFields()["name"].GetStringValue())
+	}
+	errFunc := func(err error) { t.Error(err) }
+
+	// Unchanged included data does not notify
+	core.Refresh(context.Background(), notifyFunc, errFunc)
+	includer["app/common.json"] = `{"name": "after"}`
+	core.Refresh(context.Background(), notifyFunc, errFunc)
+	if !reflect.DeepEqual([]string{"after"}, notified) {
+		t.Errorf("expected notifications [after]; got %v", notified)
+	}
+}
+
+func TestFileIncluder(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db.yaml"), []byte("host: localhost\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	includer := FileIncluder{}
+	from := filepath.Join(dir, "config.yaml")
+	if got := includer.Join(from, "db.yaml"); got != filepath.Join(dir, "db.yaml") {
+		t.Errorf("expected a path relative to the including file; got %q", got)
+	}
+	if got := includer.Join(from, "/etc/app/../db.yaml"); got != "/etc/db.yaml" {
+		t.Errorf("expected the absolute path; got %q", got)
+	}
+	names, err := includer.Glob(context.Background(), includer.Join(from, "*.yaml"))
+	if err != nil || !reflect.DeepEqual([]string{filepath.Join(dir, "db.yaml")}, names) {
+		t.Errorf("expected the matching file; got %v, %v", names, err)
+	}
+	if !MatchPattern(filepath.Join(dir, "*.yaml"), names[0]) || MatchPattern(filepath.Join(dir, "*.json"), names[0]) {
+		t.Errorf("expected the path to match *.yaml only")
+	}
+	data, err := includer.Fetch(context.Background(), names[0])
+	if err != nil || string(data) != "host: localhost\n" {
+		t.Errorf("expected the file content; got %q, %v", data, err)
+	}
+	if _, err := includer.Fetch(context.Background(), filepath.Join(dir, "missing.yaml")); !errors.Is(err, ErrNotExist) {
+		t.Errorf("expected ErrNotExist; got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Merge deep-merges configuration values, later values override earlier ones.
+// Nested structs are merged key by key, any other value (including lists) is replaced.
+// Nil values are skipped and the inputs are never modified.
+// Parameters:
+//   - values: Configuration values in ascending priority order
+//
+// Returns:
+//   - *structpb.Struct: Merged configuration data
+func Merge(values ...*structpb.Struct) *structpb.Struct {
+	merged := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		mergeStruct(merged, value)
+	}
+	return merged
+}
+
+// mergeStruct deep-merges src into dst
+func mergeStruct(dst, src *structpb.Struct) {
+	if dst.Fields == nil {
+		dst.Fields = map[string]*structpb.Value{}
+	}
+	for key, srcValue := range src.GetFields() {
+		dstValue, ok := dst.Fields[key]
+		if ok && dstValue.GetStructValue() != nil && srcValue.GetStructValue() != nil {
+			mergeStruct(dstValue.GetStructValue(), srcValue.GetStructValue())
+			continue
+		}
+		dst.Fields[key] = proto.Clone(srcValue).(*structpb.Value)
+	}
+}
@@ -0,0 +1,33 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMerge(t *testing.T) {
+	base, _ := structpb.NewStruct(map[string]any{
+		"server": map[string]any{"addr": "0.0.0.0", "port": 8080},
+		"hosts":  []any{"a", "b"},
+		"name":   "base",
+	})
+	overlay, _ := structpb.NewStruct(map[string]any{
+		"server": map[string]any{"port": 80},
+		"hosts":  []any{"c"},
+	})
+
+	merged := Merge(base, nil, overlay)
+	expected := map[string]any{
+		"server": map[string]any{"addr": "0.0.0.0", "port": float64(80)},
+		"hosts":  []any{"c"},
+		"name":   "base",
+	}
+	if !reflect.DeepEqual(merged.AsMap(), expected) {
+		t.Errorf("expected %v; got %v", expected, merged.AsMap())
+	}
+	if port := base.GetFields()["server"].GetStructValue().GetFields()["port"].GetNumberValue(); port != 8080 {
+		t.Errorf("expected base to be unchanged; got port %v", port)
+	}
+}
@@ -0,0 +1,473 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/soyacen/gonfig/format"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Middleware defines the function type for decorating a resource with additional behavior.
+type Middleware func(Resource) Resource
+
+// Wrap decorates a resource with the given middlewares.
+// The first middleware is the outermost one, so it observes calls first.
+// If the resource is a MessageResource, so is the decorated resource.
+// Parameters:
+//   - r: Resource to decorate
+//   - mws: Middlewares to apply
+//
+// Returns:
+//   - Resource: Decorated resource
+func Wrap(r Resource, mws ...Middleware) Resource {
+	for i := len(mws) - 1; i >= 0; i-- {
+		r = keepMessage(r, mws[i](r))
+	}
+	return r
+}
+
+// keepMessage makes a resource decorating a MessageResource a MessageResource as well, see messageResource
+// Parameters:
+//   - next: Decorated resource
+//   - r: Decorating resource
+//
+// Returns:
+//   - Resource: r, implementing MessageResource if next does
+func keepMessage(next Resource, r Resource) Resource {
+	nextMessage, ok := next.(MessageResource)
+	if !ok {
+		return r
+	}
+	if _, ok := r.(MessageResource); ok {
+		return r
+	}
+	return &messageResource{Resource: r, next: nextMessage}
+}
+
+// messageResource keeps the MessageResource interface of a decorated resource.
+// Messages are decoded from the values of the decorating resource, so that middlewares such as Transform apply,
+// and message types are registered with the decorated resource, so that formats decoding data directly
+// into messages, such as binpb, can parse its data.
+type messageResource struct {
+	Resource
+	// next is the decorated resource
+	next MessageResource
+	// mutex protects value and sensitive
+	mutex sync.Mutex
+	// value is the configuration data of the latest load or notification
+	value *structpb.Struct
+	// sensitive holds the values marked as sensitive by the transformers of the decorating resource
+	sensitive *format.Sensitive
+}
+
+// Load loads the configuration through the decorating resource and remembers it for DecodeMessage
+func (r *messageResource) Load(ctx context.Context) (*structpb.Struct, error) {
+	sensitive := &format.Sensitive{}
+	value, err := r.Resource.Load(format.ContextWithSensitive(ctx, sensitive))
+	if err != nil {
+		return nil, err
+	}
+	r.store(value, sensitive)
+	return value, nil
+}
+
+// Watch watches the decorating resource and remembers notified configurations for DecodeMessage
+func (r *messageResource) Watch(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+	if notifyFunc == nil {
+		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+	}
+	// Notifications are transformed with the context of Watch, the set keeps the latest value of every path
+	sensitive := &format.Sensitive{}
+	return r.Resource.Watch(
+		format.ContextWithSensitive(ctx, sensitive),
+		func(value *structpb.Struct) {
+			r.store(value, sensitive)
+			notifyFunc(value)
+		},
+		errFunc,
+	)
+}
+
+// LoadMessage registers the message type with the decorated resource, loads the configuration
+// through the decorating resource and decodes it into the message
+func (r *messageResource) LoadMessage(ctx context.Context, message proto.Message) error {
+	r.next.RegisterMessage(message)
+	sensitive := &format.Sensitive{}
+	value, err := r.Resource.Load(format.ContextWithSensitive(ctx, sensitive))
+	if err != nil {
+		return err
+	}
+	r.store(value, sensitive)
+	return sensitive.Redact(format.StructToMessage(value, message))
+}
+
+// DecodeMessage decodes the configuration of the latest load or notification into a message
+func (r *messageResource) DecodeMessage(message proto.Message) error {
+	r.mutex.Lock()
+	value, sensitive := r.value, r.sensitive
+	r.mutex.Unlock()
+	if value == nil {
+		return fmt.Errorf("gonfig: no configuration loaded or notified")
+	}
+	return sensitive.Redact(format.StructToMessage(value, message))
+}
+
+// DecodeValue decodes a configuration loaded or notified by the decorating resource into a message
+func (r *messageResource) DecodeValue(value *structpb.Struct, message proto.Message) error {
+	r.mutex.Lock()
+	sensitive := r.sensitive
+	r.mutex.Unlock()
+	return sensitive.Redact(format.StructToMessage(value, message))
+}
+
+// RegisterMessage registers the message type with the decorated resource
+func (r *messageResource) RegisterMessage(message proto.Message) {
+	r.next.RegisterMessage(message)
+}
+
+// store remembers the configuration of a load or notification and its sensitive values
+func (r *messageResource) store(value *structpb.Struct, sensitive *format.Sensitive) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.value, r.sensitive = value, sensitive
+}
+
+// LoadFunc defines the function type implementing Resource.Load.
+type LoadFunc func(ctx context.Context) (*structpb.Struct, error)
+
+// WatchFunc defines the function type implementing Resource.Watch.
+type WatchFunc func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error)
+
+// Funcs adapts a pair of functions to the Resource interface.
+// It is convenient for writing middlewares that only decorate one of the methods.
+// Wrap keeps the MessageResource interface of the decorated resource, which Funcs does not implement.
+type Funcs struct {
+	// LoadFunc implements Load
+	LoadFunc LoadFunc
+	// WatchFunc implements Watch
+	WatchFunc WatchFunc
+}
+
+// Load calls LoadFunc
+func (f Funcs) Load(ctx context.Context) (*structpb.Struct, error) {
+	return f.LoadFunc(ctx)
+}
+
+// Watch calls WatchFunc
+func (f Funcs) Watch(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+	return f.WatchFunc(ctx, notifyFunc, errFunc)
+}
+
+// Logging logs loads, notifications and errors of a resource.
+// Parameters:
+//   - logger: Logger to write to, slog.Default() is used if nil
+func Logging(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next Resource) Resource {
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				start := time.Now()
+				value, err := next.Load(ctx)
+				if err != nil {
+					logger.ErrorContext(ctx, "gonfig: failed to load resource", slog.Duration("duration", time.Since(start)), slog.String("error", err.Error()))
+					return nil, err
+				}
+				logger.InfoContext(ctx, "gonfig: loaded resource", slog.Duration("duration", time.Since(start)))
+				return value, nil
+			},
+			WatchFunc: func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+				if notifyFunc == nil {
+					return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+				}
+				return next.Watch(
+					ctx,
+					func(value *structpb.Struct) {
+						logger.InfoContext(ctx, "gonfig: resource changed")
+						notifyFunc(value)
+					},
+					func(err error) {
+						logger.ErrorContext(ctx, "gonfig: failed to watch resource", slog.String("error", err.Error()))
+						if errFunc != nil {
+							errFunc(err)
+						}
+					},
+				)
+			},
+		})
+	}
+}
+
+// Recorder receives metrics about a resource.
+type Recorder interface {
+	// ObserveLoad records the duration and outcome of a Load call
+	ObserveLoad(duration time.Duration, err error)
+	// ObserveNotify records a configuration update delivered by Watch
+	ObserveNotify()
+	// ObserveError records an error reported by Watch
+	ObserveError(err error)
+}
+
+// Metrics reports loads, notifications and errors of a resource to a Recorder.
+// Parameters:
+//   - recorder: Recorder receiving the metrics
+func Metrics(recorder Recorder) Middleware {
+	return func(next Resource) Resource {
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				start := time.Now()
+				value, err := next.Load(ctx)
+				recorder.ObserveLoad(time.Since(start), err)
+				return value, err
+			},
+			WatchFunc: func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+				if notifyFunc == nil {
+					return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+				}
+				// Keep the default error logging of resources when no handler is provided
+				if errFunc == nil {
+					errFunc = func(err error) {
+						slog.Error("gonfig: failed to watch resource", slog.String("error", err.Error()))
+					}
+				}
+				return next.Watch(
+					ctx,
+					func(value *structpb.Struct) {
+						recorder.ObserveNotify()
+						notifyFunc(value)
+					},
+					func(err error) {
+						recorder.ObserveError(err)
+						errFunc(err)
+					},
+				)
+			},
+		})
+	}
+}
+
+// Transform applies transformers in order to the values loaded and notified by a resource,
+// e.g. to decrypt the values of resources that do not accept WithTransformers.
+// Values failing to transform are reported to the error handler instead of being notified.
+// Parameters:
+//   - transformers: Transformers to apply
+func Transform(transformers ...Transformer) Middleware {
+	transform := func(ctx context.Context, value *structpb.Struct) (*structpb.Struct, error) {
+		for _, transformer := range transformers {
+			var err error
+			if value, err = transformer.Transform(ctx, value); err != nil {
+				return nil, err
+			}
+		}
+		return value, nil
+	}
+	return func(next Resource) Resource {
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				value, err := next.Load(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return transform(ctx, value)
+			},
+			WatchFunc: func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+				if notifyFunc == nil {
+					return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+				}
+				// Keep the default error logging of resources when no handler is provided
+				if errFunc == nil {
+					errFunc = func(err error) {
+						slog.Error("gonfig: failed to watch resource", slog.String("error", err.Error()))
+					}
+				}
+				return next.Watch(
+					ctx,
+					func(value *structpb.Struct) {
+						value, err := transform(ctx, value)
+						if err != nil {
+							errFunc(err)
+							return
+						}
+						notifyFunc(value)
+					},
+					errFunc,
+				)
+			},
+		})
+	}
+}
+
+// Cache serves Load from memory while the latest value is younger than ttl.
+// Values delivered by Watch refresh the cache.
+// Parameters:
+//   - ttl: How long a loaded value stays fresh
+func Cache(ttl time.Duration) Middleware {
+	return func(next Resource) Resource {
+		var mutex sync.Mutex
+		var cached *structpb.Struct
+		var expireAt time.Time
+		store := func(value *structpb.Struct) {
+			mutex.Lock()
+			cached = value
+			expireAt = time.Now().Add(ttl)
+			mutex.Unlock()
+		}
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				mutex.Lock()
+				if cached != nil && time.Now().Before(expireAt) {
+					value := proto.Clone(cached).(*structpb.Struct)
+					mutex.Unlock()
+					return value, nil
+				}
+				mutex.Unlock()
+				value, err := next.Load(ctx)
+				if err != nil {
+					return nil, err
+				}
+				store(proto.Clone(value).(*structpb.Struct))
+				return value, nil
+			},
+			WatchFunc: func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+				if notifyFunc == nil {
+					return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+				}
+				return next.Watch(
+					ctx,
+					func(value *structpb.Struct) {
+						store(proto.Clone(value).(*structpb.Struct))
+						notifyFunc(value)
+					},
+					errFunc,
+				)
+			},
+		})
+	}
+}
+
+// Retry retries failed Load calls and Watch setups.
+// Parameters:
+//   - attempts: Maximum number of attempts, including the first one
+//   - backoff: Delay between attempts, doubled after every failure
+func Retry(attempts int, backoff time.Duration) Middleware {
+	if attempts < 1 {
+		attempts = 1
+	}
+	retry := func(ctx context.Context, fn func() error) error {
+		delay := backoff
+		var errs []error
+		for i := 0; i < attempts; i++ {
+			err := fn()
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+			if i == attempts-1 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Join(append(errs, ctx.Err())...)
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		return errors.Join(errs...)
+	}
+	return func(next Resource) Resource {
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				var value *structpb.Struct
+				err := retry(ctx, func() error {
+					var err error
+					value, err = next.Load(ctx)
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+				return value, nil
+			},
+			WatchFunc: func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+				var stop StopFunc
+				err := retry(ctx, func() error {
+					var err error
+					stop, err = next.Watch(ctx, notifyFunc, errFunc)
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+				return stop, nil
+			},
+		})
+	}
+}
+
+// Timeout bounds the duration of Load calls.
+// Watch is not bounded because its context controls the lifetime of the watcher.
+// Parameters:
+//   - timeout: Maximum duration of a Load call
+func Timeout(timeout time.Duration) Middleware {
+	return func(next Resource) Resource {
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				return next.Load(ctx)
+			},
+			WatchFunc: next.Watch,
+		})
+	}
+}
+
+// Dedup suppresses notifications whose value equals the previously loaded or notified one.
+// Unlike the byte comparison performed by Core, it also suppresses changes that do not
+// affect the parsed data, such as comments or formatting.
+func Dedup() Middleware {
+	return func(next Resource) Resource {
+		var mutex sync.Mutex
+		var pre *structpb.Struct
+		// changed stores value and reports whether it differs from the previous one
+		changed := func(value *structpb.Struct) bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if pre != nil && proto.Equal(pre, value) {
+				return false
+			}
+			pre = proto.Clone(value).(*structpb.Struct)
+			return true
+		}
+		return keepMessage(next, Funcs{
+			LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+				value, err := next.Load(ctx)
+				if err != nil {
+					return nil, err
+				}
+				changed(value)
+				return value, nil
+			},
+			WatchFunc: func(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+				if notifyFunc == nil {
+					return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+				}
+				return next.Watch(
+					ctx,
+					func(value *structpb.Struct) {
+						if changed(value) {
+							notifyFunc(value)
+						}
+					},
+					errFunc,
+				)
+			},
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// countingResource counts Load calls and fails the first failures of them
+type countingResource struct {
+	loads      int
+	failures   int
+	notifyFunc NotifyFunc
+}
+
+func (r *countingResource) Load(ctx context.Context) (*structpb.Struct, error) {
+	r.loads++
+	if r.loads <= r.failures {
+		return nil, errors.New("unavailable")
+	}
+	return structpb.NewStruct(map[string]any{"loads": r.loads})
+}
+
+func (r *countingResource) Watch(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error) {
+	r.notifyFunc = notifyFunc
+	return func(context.Context) error { return nil }, nil
+}
+
+func TestWrap_Order(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Resource) Resource {
+			return Funcs{
+				LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+					calls = append(calls, name)
+					return next.Load(ctx)
+				},
+				WatchFunc: next.Watch,
+			}
+		}
+	}
+	r := Wrap(&countingResource{}, trace("outer"), trace("inner"))
+	if _, err := r.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0] != "outer" || calls[1] != "inner" {
+		t.Errorf("expected [outer inner]; got %v", calls)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	inner := &countingResource{failures: 2}
+	r := Wrap(inner, Retry(3, time.Millisecond))
+	if _, err := r.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if inner.loads != 3 {
+		t.Errorf("expected 3 loads; got %d", inner.loads)
+	}
+
+	inner = &countingResource{failures: 5}
+	r = Wrap(inner, Retry(2, time.Millisecond))
+	if _, err := r.Load(context.Background()); err == nil {
+		t.Error("expected error after exhausting attempts")
+	}
+}
+
+func TestCache(t *testing.T) {
+	inner := &countingResource{}
+	r := Wrap(inner, Cache(time.Hour))
+	for i := 0; i < 3; i++ {
+		if _, err := r.Load(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.loads != 1 {
+		t.Errorf("expected 1 load; got %d", inner.loads)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	r := Wrap(Funcs{
+		LoadFunc: func(ctx context.Context) (*structpb.Struct, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}, Timeout(10*time.Millisecond))
+	if _, err := r.Load(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded; got %v", err)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	inner := &countingResource{}
+	r := Wrap(inner, Dedup())
+	var notified int
+	if _, err := r.Watch(context.Background(), func(*structpb.Struct) { notified++ }, nil); err != nil {
+		t.Fatal(err)
+	}
+	value, _ := structpb.NewStruct(map[string]any{"key": "value"})
+	inner.notifyFunc(value)
+	inner.notifyFunc(value)
+	changed, _ := structpb.NewStruct(map[string]any{"key": "changed"})
+	inner.notifyFunc(changed)
+	if notified != 2 {
+		t.Errorf("expected 2 notifications; got %d", notified)
+	}
+}
+
+// countingRecorder counts observed metrics
+type countingRecorder struct {
+	loads, notifies, errs int
+}
+
+func (r *countingRecorder) ObserveLoad(time.Duration, error) { r.loads++ }
+func (r *countingRecorder) ObserveNotify()                   { r.notifies++ }
+func (r *countingRecorder) ObserveError(error)               { r.errs++ }
+
+func TestMetrics(t *testing.T) {
+	inner := &countingResource{}
+	recorder := &countingRecorder{}
+	r := Wrap(inner, Metrics(recorder))
+	if _, err := r.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Watch(context.Background(), func(*structpb.Struct) {}, nil); err != nil {
+		t.Fatal(err)
+	}
+	inner.notifyFunc(&structpb.Struct{})
+	if recorder.loads != 1 || recorder.notifies != 1 {
+		t.Errorf("expected 1 load and 1 notify; got %+v", recorder)
+	}
+}
+
+// doubleTransformer doubles the "loads" field and fails for odd values above 1
+type doubleTransformer struct{}
+
+func (doubleTransformer) Transform(ctx context.Context, value *structpb.Struct) (*structpb.Struct, error) {
+	loads := value.GetFields()["loads"].GetNumberValue()
+	if loads > 1 && int(loads)%2 == 1 {
+		return nil, errors.New("odd value")
+	}
+	return structpb.NewStruct(map[string]any{"loads": loads * 2})
+}
+
+func TestTransform(t *testing.T) {
+	inner := &countingResource{}
+	r := Wrap(inner, Transform(doubleTransformer{}))
+	value, err := r.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.GetFields()["loads"].GetNumberValue(); got != 2 {
+		t.Errorf("expected 2; got %v", got)
+	}
+
+	var notified []float64
+	var errs []error
+	if _, err := r.Watch(context.Background(), func(value *structpb.Struct) {
+		notified = append(notified, value.GetFields()["loads"].GetNumberValue())
+	}, func(err error) {
+		errs = append(errs, err)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, loads := range []float64{2, 3} {
+		value, err := structpb.NewStruct(map[string]any{"loads": loads})
+		if err != nil {
+			t.Fatal(err)
+		}
+		inner.notifyFunc(value)
+	}
+	if len(notified) != 1 || notified[0] != 4 || len(errs) != 1 {
+		t.Errorf("expected notifications [4] and 1 error; got %v and %v", notified, errs)
+	}
+}
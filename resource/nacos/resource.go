@@ -2,23 +2,33 @@
 package nacos
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"log/slog"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
 	"sync"
-	"sync/atomic"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
 	"github.com/soyacen/gonfig/format"
 	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
-var _ resource.Resource = (*Resource)(nil)
+var (
+	_ resource.MessageResource = (*Resource)(nil)
+	_ resource.ValueDecoder    = (*Resource)(nil)
+)
+
+// ConfigTypeClient is implemented by Nacos config clients able to report the "Type" metadata of a
+// configuration, e.g. "yaml", "json", "properties" or "text".
+// The IConfigClient of nacos-sdk-go does not expose it; wrap the client to provide it, e.g. from the Nacos open API.
+type ConfigTypeClient interface {
+	// GetConfigType returns the type of the configuration identified by the group and dataId of param
+	GetConfigType(param vo.ConfigParam) (string, error)
+}
 
 // Resource represents a configuration resource in Nacos server
 type Resource struct {
@@ -28,12 +38,10 @@ type Resource struct {
 	group string
 	// dataId Configuration data ID in Nacos
 	dataId string
-	// extension of the configuration
-	ext string
-	// formatter for parsing configuration
-	formatter format.Formatter
-	// pre atomic storage for configuration pre
-	pre atomic.Value
+	// dataIds Configuration data ID followed by its profile-specific variants
+	dataIds []string
+	// core for parsing configuration and suppressing unchanged payloads
+	core *resource.Core
 }
 
 // Load retrieves configuration from Nacos server and parses it into structpb.Struct
@@ -45,35 +53,69 @@ type Resource struct {
 //   - *structpb.Struct: Parsed configuration data
 //   - error: Any error that occurred during loading or parsing
 func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
-	data, err := r.load(ctx)
-	if err != nil {
-		return nil, err
-	}
-	parsed, err := r.formatter.Parse(data)
-	if err != nil {
-		return nil, err
-	}
-	r.pre.Store(data)
-	return parsed, nil
+	return r.core.Load(ctx)
 }
 
-// load is an internal helper function to fetch raw data from Nacos server
+// LoadMessage retrieves the configuration and decodes it into a message
+// Formatters implementing format.MessageFormatter decode the data directly into the message
 // Parameters:
 //   - ctx: Context for cancellation and timeouts
+//   - message: Message the configuration data is decoded into
 //
 // Returns:
-//   - []byte: Raw configuration data from Nacos
-//   - error: Any error that occurred while fetching from Nacos
-func (r *Resource) load(ctx context.Context) ([]byte, error) {
-	content, err := r.client.GetConfig(vo.ConfigParam{Group: r.group, DataId: r.dataId})
-	if err != nil {
-		return nil, err
+//   - error: Any error that occurred during loading or decoding
+func (r *Resource) LoadMessage(ctx context.Context, message proto.Message) error {
+	return r.core.LoadMessage(ctx, message)
+}
+
+// DecodeMessage decodes the configuration of the latest load or change into a message
+// Parameters:
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (r *Resource) DecodeMessage(message proto.Message) error {
+	return r.core.DecodeMessage(message)
+}
+
+// DecodeValue decodes a configuration returned by Load or notified by Watch into a message,
+// from the data it was parsed from even if it has changed since
+// Parameters:
+//   - value: Configuration data returned by Load or notified by Watch
+//   - message: Message the configuration data is decoded into
+//
+// Returns:
+//   - error: Any error that occurred during decoding
+func (r *Resource) DecodeValue(value *structpb.Struct, message proto.Message) error {
+	return r.core.DecodeValue(value, message)
+}
+
+// RegisterMessage registers the type of the message the configuration is decoded into,
+// so that formats decoding data directly into messages work with Watch without a previous LoadMessage
+// Parameters:
+//   - message: Message of the type the configuration data is decoded into
+func (r *Resource) RegisterMessage(message proto.Message) {
+	r.core.RegisterMessage(message)
+}
+
+// load is an internal helper function returning a fetch function for raw data from Nacos server
+// Parameters:
+//   - dataId: Configuration data ID in Nacos
+//
+// Returns:
+//   - resource.FetchFunc: Function fetching the raw configuration data from Nacos
+func (r *Resource) load(dataId string) resource.FetchFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		content, err := r.client.GetConfig(vo.ConfigParam{Group: r.group, DataId: dataId})
+		if err != nil {
+			return nil, err
+		}
+		return []byte(content), nil
 	}
-	return []byte(content), nil
 }
 
 // Watch sets up a watcher for configuration changes in Nacos
-// It registers a listener that monitors changes to the specific group and dataId and notifies subscribers
+// It registers listeners that monitor changes to the dataId and its profile-specific variants and notifies subscribers
 // Parameters:
 //   - ctx: Context for cancellation
 //   - notifyFunc: Callback function for configuration updates
@@ -83,64 +125,39 @@ func (r *Resource) load(ctx context.Context) ([]byte, error) {
 //   - resource.StopFunc: Function to stop watching
 //   - error: Any immediate error during setup
 func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
-	// Validate notify function
-	if notifyFunc == nil {
-		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
-	}
-
-	// Set default error handler if none provided
-	if errFunc == nil {
-		errFunc = func(err error) {
-			slog.Error("gonfig: failed to watch nacos", slog.String("error", err.Error()))
-		}
+	// Validate arguments and set default error handler
+	errFunc, err := r.core.PrepareWatch(ctx, notifyFunc, errFunc)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if context is already cancelled
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
+	// Listen to the included dataIds, the set of included dataIds may change with every change
+	includes := &includeWatcher{r: r, ctx: ctx, notifyFunc: notifyFunc, errFunc: errFunc, dataIds: make(map[string]bool)}
 
-	// Set up handler for configuration change events
-	onChange := func(_, _, _, value string) {
-		data := []byte(value)
-		preData := r.pre.Load()
-		// Skip if configuration hasn't changed
-		if preData != nil && bytes.Equal(preData.([]byte), data) {
-			return
+	// Register a listener for every dataId with Nacos client
+	for i, dataId := range r.dataIds {
+		// Set up handler for configuration change events
+		onChange := func(_, _, _, value string) {
+			// Parse and notify subscribers of the change
+			r.core.NotifyLayer(i, []byte(value), notifyFunc, errFunc)
+			includes.sync()
 		}
-		// Parse new configuration data
-		newValue, err := r.formatter.Parse(data)
-		if err != nil {
-			errFunc(err)
-			return
+		if err := r.client.ListenConfig(vo.ConfigParam{Group: r.group, DataId: dataId, OnChange: onChange}); err != nil {
+			r.cancel(r.dataIds[:i], errFunc)
+			return nil, err
 		}
-		// Notify subscribers of the change
-		notifyFunc(newValue)
-		// Store new data for future comparisons
-		r.pre.Store(data)
 	}
+	includes.sync()
 
-	// Register listener with Nacos client
-	if err := r.client.ListenConfig(vo.ConfigParam{Group: r.group, DataId: r.dataId, OnChange: onChange}); err != nil {
-		return nil, err
-	}
-
-	// Create stop function with sync.Once to ensure it's only called once
-	stopC := make(chan struct{})
-	var onceStop sync.Once
-	stop := func(ctx context.Context) error {
-		onceStop.Do(func() { close(stopC) })
-		return nil
-	}
+	// Create stop function
+	stop, stopC := resource.NewStopFunc()
 
 	// Start a goroutine to handle context cancellation and cleanup
 	go func() {
+		// Cancel listeners when goroutine exits
 		defer func() {
-			// Cancel listener when goroutine exits
-			if err := r.client.CancelListenConfig(vo.ConfigParam{Group: r.group, DataId: r.dataId}); err != nil {
-				errFunc(err)
-				return
-			}
+			r.cancel(r.dataIds, errFunc)
+			includes.stop()
 		}()
 		select {
 		case <-ctx.Done():
@@ -157,35 +174,223 @@ func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, er
 	return stop, nil
 }
 
+// cancel cancels the listeners of the given dataIds
+// Parameters:
+//   - dataIds: Configuration data IDs whose listeners are cancelled
+//   - errFunc: Callback function for error reporting
+func (r *Resource) cancel(dataIds []string, errFunc resource.ErrFunc) {
+	for _, dataId := range dataIds {
+		if err := r.client.CancelListenConfig(vo.ConfigParam{Group: r.group, DataId: dataId}); err != nil {
+			errFunc(err)
+		}
+	}
+}
+
+// includeWatcher listens to the dataIds included by the configuration and parses the configuration again when they change.
+// Include patterns are matched again when the configuration or an included dataId changes.
+type includeWatcher struct {
+	r          *Resource
+	ctx        context.Context
+	notifyFunc resource.NotifyFunc
+	errFunc    resource.ErrFunc
+	// mutex protects dataIds and stopped
+	mutex sync.Mutex
+	// dataIds are the included dataIds with a listener
+	dataIds map[string]bool
+	stopped bool
+}
+
+// sync listens to newly included dataIds and cancels the listeners of dataIds no longer included
+func (w *includeWatcher) sync() {
+	includes, _ := w.r.core.Includes()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stopped {
+		return
+	}
+	for dataId := range w.dataIds {
+		if !slices.Contains(includes, dataId) {
+			w.r.cancel([]string{dataId}, w.errFunc)
+			delete(w.dataIds, dataId)
+		}
+	}
+	for _, dataId := range includes {
+		if w.dataIds[dataId] || slices.Contains(w.r.dataIds, dataId) {
+			continue
+		}
+		onChange := func(_, _, _, _ string) {
+			w.r.core.Refresh(w.ctx, w.notifyFunc, w.errFunc)
+			w.sync()
+		}
+		if err := w.r.client.ListenConfig(vo.ConfigParam{Group: w.r.group, DataId: dataId, OnChange: onChange}); err != nil {
+			w.errFunc(err)
+			continue
+		}
+		w.dataIds[dataId] = true
+	}
+}
+
+// stop cancels the listeners of the included dataIds
+func (w *includeWatcher) stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.stopped = true
+	for dataId := range w.dataIds {
+		w.r.cancel([]string{dataId}, w.errFunc)
+	}
+}
+
+// includer resolves include directives against the dataIds of the group
+type includer struct {
+	r *Resource
+}
+
+// Join resolves an included dataId relative to the "directory" of the including dataId,
+// a leading "/" makes it relative to the group
+func (i includer) Join(from string, name string) string {
+	if strings.HasPrefix(name, "/") {
+		return strings.TrimPrefix(path.Clean(name), "/")
+	}
+	return path.Join(path.Dir(from), name)
+}
+
+// Glob returns the dataIds of the group matching a pattern
+func (i includer) Glob(ctx context.Context, pattern string) ([]string, error) {
+	prefix := pattern
+	if n := strings.IndexAny(pattern, "*?["); n >= 0 {
+		prefix = pattern[:n]
+	}
+	var names []string
+	for pageNo := 1; ; pageNo++ {
+		page, err := i.r.client.SearchConfig(vo.SearchConfigParam{
+			Search:   "blur",
+			Group:    i.r.group,
+			DataId:   prefix + "*",
+			PageNo:   pageNo,
+			PageSize: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.PageItems {
+			if matched, _ := path.Match(pattern, item.DataId); matched && !slices.Contains(names, item.DataId) {
+				names = append(names, item.DataId)
+			}
+		}
+		if pageNo >= page.PagesAvailable {
+			break
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Fetch retrieves the content of an included dataId
+func (i includer) Fetch(ctx context.Context, name string) ([]byte, error) {
+	data, err := i.r.load(name)(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Nacos returns empty content for missing dataIds
+	if len(data) == 0 {
+		return nil, fmt.Errorf("gonfig: nacos dataId %q not found: %w", name, resource.ErrNotExist)
+	}
+	return data, nil
+}
+
 // New creates a new Nacos configuration resource
-// It validates the dataId extension and finds an appropriate formatter
+// It finds the formatter of the format set by resource.WithFormat or of the dataId extension;
+// without either, the type metadata of the configuration is used if the client implements ConfigTypeClient,
+// otherwise the format is detected from the content.
+// For every active profile, the profile-specific dataId (e.g. "config.prod.yaml" for "config.yaml")
+// in the same group is deep-merged on top of the configuration if it exists.
+// Include directives (see resource.IncludeKey) name dataIds of the same group relative to the including dataId;
+// the included dataIds are listened to as well.
 // Parameters:
 //   - client: Nacos config client
 //   - group: Configuration group in Nacos
 //   - dataId: Configuration data ID in Nacos
+//   - opts: Options such as resource.WithProfiles, resource.WithFormat and resource.WithRegistry
 //
 // Returns:
 //   - *Resource: New Nacos resource instance
 //   - error: Any error during initialization
-func New(client config_client.IConfigClient, group string, dataId string) (*Resource, error) {
-	// Extract dataId extension
-	ext := strings.TrimPrefix(filepath.Ext(dataId), ".")
-	if ext == "" {
-		return nil, fmt.Errorf("config: dataId extension is empty")
+func New(client config_client.IConfigClient, group string, dataId string, opts ...resource.Option) (*Resource, error) {
+	options := resource.NewOptions(opts...)
+
+	// Find appropriate formatter for the explicit format or the dataId extension, or sniff the format
+	formatter, err := options.Formatter(dataId)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find appropriate formatter for the dataId extension
-	formatter, ok := format.GetFormatter(ext)
-	if !ok {
-		return nil, fmt.Errorf("config: not found formatter for %s", ext)
+	// Without explicit format and extension, prefer the type metadata stored on the server
+	if options.Format == "" && options.Ext(dataId) == "" {
+		if typeClient, ok := client.(ConfigTypeClient); ok {
+			formatter, err = options.WrapFormatter(&typeFormatter{client: typeClient, group: group, dataId: dataId, registry: options.FormatRegistry()}, dataId)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Return new resource instance
-	return &Resource{
-		client:    client,
-		group:     group,
-		dataId:    dataId,
-		ext:       ext,
-		formatter: formatter,
-	}, nil
+	r := &Resource{
+		client:  client,
+		group:   group,
+		dataId:  dataId,
+		dataIds: []string{dataId},
+	}
+	r.core = resource.NewCore("nacos", r.load(dataId), formatter)
+	for _, profile := range options.Profiles {
+		profileDataId := options.ProfileName(dataId, profile)
+		r.dataIds = append(r.dataIds, profileDataId)
+		r.core.AddOverlay(r.load(profileDataId))
+	}
+	r.core.EnableIncludes(includer{r: r}, options, r.dataIds...)
+	for _, transformer := range options.Transformers {
+		r.core.AddTransformer(transformer)
+	}
+	return r, nil
+}
+
+// typeFormatter parses data with the formatter of the type metadata of a configuration.
+// The type is looked up once; types without a registered formatter, such as "text", fall back to sniffing.
+type typeFormatter struct {
+	client   ConfigTypeClient
+	group    string
+	dataId   string
+	registry *format.Registry
+	// mutex protects formatter
+	mutex sync.Mutex
+	// formatter is the formatter of the type, nil until looked up
+	formatter format.Formatter
+}
+
+// Parse parses data with the formatter of the type metadata
+func (f *typeFormatter) Parse(data []byte) (*structpb.Struct, error) {
+	formatter, err := f.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return formatter.Parse(data)
+}
+
+// resolve looks up the formatter of the type metadata
+func (f *typeFormatter) resolve() (format.Formatter, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.formatter != nil {
+		return f.formatter, nil
+	}
+	typ, err := f.client.GetConfigType(vo.ConfigParam{Group: f.group, DataId: f.dataId})
+	if err != nil {
+		return nil, err
+	}
+	formatter, ok := f.registry.Get(strings.ToLower(typ))
+	if !ok {
+		formatter = format.Sniffer{Registry: f.registry}
+	}
+	f.formatter = formatter
+	return formatter, nil
 }
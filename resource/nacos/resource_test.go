@@ -3,15 +3,18 @@ package nacos
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/soyacen/gonfig/format"
-	"github.com/soyacen/gonfig/format/env"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/soyacen/gonfig/format"
+	"github.com/soyacen/gonfig/format/env"
+	_ "github.com/soyacen/gonfig/format/json"
 	_ "golang.org/x/crypto/chacha20"
 	_ "golang.org/x/net/http2"
 	_ "golang.org/x/sync/singleflight"
@@ -167,3 +170,75 @@ func TestResource_Watch_Nacos(t *testing.T) {
 		t.Errorf("expected value 'updated'; got %q", val)
 	}
 }
+
+// typeClient is a fake config client reporting the type metadata of configurations
+type typeClient struct {
+	config_client.IConfigClient
+	typ     string
+	content string
+}
+
+func (c *typeClient) GetConfig(param vo.ConfigParam) (string, error) {
+	return c.content, nil
+}
+
+func (c *typeClient) GetConfigType(param vo.ConfigParam) (string, error) {
+	return c.typ, nil
+}
+
+func TestResource_Load_ConfigType(t *testing.T) {
+	format.RegisterFormatter("env", env.Env{})
+	r, err := New(&typeClient{typ: "env", content: "KEY=value"}, "test", "api-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := r.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := content.GetFields()["KEY"].GetStringValue(); got != "value" {
+		t.Errorf("expected 'value'; got %q", got)
+	}
+}
+
+// mapClient is a fake config client serving the configurations of a map by dataId
+type mapClient struct {
+	config_client.IConfigClient
+	contents map[string]string
+}
+
+func (c *mapClient) GetConfig(param vo.ConfigParam) (string, error) {
+	return c.contents[param.DataId], nil
+}
+
+func (c *mapClient) SearchConfig(param vo.SearchConfigParam) (*model.ConfigPage, error) {
+	page := &model.ConfigPage{PageNumber: param.PageNo, PagesAvailable: 1}
+	for dataId, content := range c.contents {
+		if strings.HasPrefix(dataId, strings.TrimSuffix(param.DataId, "*")) {
+			page.PageItems = append(page.PageItems, model.ConfigItem{DataId: dataId, Group: param.Group, Content: content})
+		}
+	}
+	return page, nil
+}
+
+func TestResource_Load_Include(t *testing.T) {
+	client := &mapClient{contents: map[string]string{
+		"api.json":       `{"$include": ["common.json", "shared-*.json"], "name": "api"}`,
+		"common.json":    `{"name": "common", "log": "info"}`,
+		"shared-db.json": `{"db": "primary"}`,
+		"shared-mq.json": `{"mq": "kafka"}`,
+		"unrelated.json": `{"unrelated": true}`,
+	}}
+	r, err := New(client, "test", "api.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := r.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"name": "api", "log": "info", "db": "primary", "mq": "kafka"}
+	if !reflect.DeepEqual(expected, content.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, content.AsMap())
+	}
+}
@@ -0,0 +1,250 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/soyacen/gonfig/format"
+)
+
+// ProfilesEnv is the environment variable holding the comma-separated list of active profiles.
+const ProfilesEnv = "GONFIG_PROFILES"
+
+// Options holds the settings shared by the built-in resources.
+type Options struct {
+	// Profiles are the active profiles layered on top of the base source, in ascending priority order
+	Profiles []string
+	// Format is the name of the format of the data, e.g. "yaml", overriding the extension of the source name
+	Format string
+	// Registry provides the formatters, format.DefaultRegistry if nil
+	Registry *format.Registry
+	// Transformers transform the parsed data of every source in order, e.g. decrypting values
+	Transformers []Transformer
+	// FormatOptions are applied to the formatters of the sources, e.g. format.WithTemplate
+	FormatOptions []format.Option
+	// QuietMissing stops reporting the disappearance of the watched base source
+	QuietMissing bool
+	// ProfileSeparator separates the base name from the profile in the names of profile-specific sources, "." if empty
+	ProfileSeparator string
+}
+
+// Option defines the function type for configuring Options.
+type Option func(*Options)
+
+// WithProfiles sets the active profiles, overriding the GONFIG_PROFILES environment variable.
+// For a base source named "config.yaml", the profile "prod" layers "config.prod.yaml" on top of it.
+// Parameters:
+//   - profiles: Active profiles in ascending priority order
+func WithProfiles(profiles ...string) Option {
+	return func(o *Options) {
+		o.Profiles = profiles
+	}
+}
+
+// WithProfileSeparator sets the separator between the base name and the profile in the names of profile-specific sources.
+// With the separator "-", the profile "prod" of "app.yaml" is "app-prod.yaml" as in Spring, instead of "app.prod.yaml".
+// Parameters:
+//   - separator: Separator inserted before the profile
+func WithProfileSeparator(separator string) Option {
+	return func(o *Options) {
+		o.ProfileSeparator = separator
+	}
+}
+
+// WithFormat sets the format of the data explicitly, for sources whose name has no or a misleading extension.
+// Parameters:
+//   - format: Name of a registered format, e.g. "yaml"
+func WithFormat(format string) Option {
+	return func(o *Options) {
+		o.Format = format
+	}
+}
+
+// WithRegistry sets the registry providing the formatters instead of format.DefaultRegistry.
+// Parameters:
+//   - registry: Registry of formatters
+func WithRegistry(registry *format.Registry) Option {
+	return func(o *Options) {
+		o.Registry = registry
+	}
+}
+
+// WithTransformers adds transformers applied in order to the parsed data of every source,
+// such as the decrypter of the crypt package.
+// Parameters:
+//   - transformers: Transformers to add
+func WithTransformers(transformers ...Transformer) Option {
+	return func(o *Options) {
+		o.Transformers = append(o.Transformers, transformers...)
+	}
+}
+
+// WithFormatOptions adds options applied to the formatters of the sources,
+// e.g. format.WithKeyCase, without registering the formatters again.
+// Parameters:
+//   - opts: Format options to add
+func WithFormatOptions(opts ...format.Option) Option {
+	return func(o *Options) {
+		o.FormatOptions = append(o.FormatOptions, opts...)
+	}
+}
+
+// WithTemplate renders the raw data of the sources as text/template templates before they are parsed,
+// see format.WithTemplate. Data is rendered on every Load and, while watching, only when the source data changes:
+// a change of the environment variables or of the results of the functions alone is picked up by the next Load
+// or change of the source data, not by Watch.
+// Parameters:
+//   - funcs: Additional template functions, may be nil
+func WithTemplate(funcs template.FuncMap) Option {
+	return WithFormatOptions(format.WithTemplate(funcs))
+}
+
+// WithQuietMissing stops reporting the disappearance of the watched base source, e.g. for files that editors
+// remove before writing them again. It only hides the report: the last configuration is kept until the source
+// comes back either way. It is supported by the file resource.
+func WithQuietMissing() Option {
+	return func(o *Options) {
+		o.QuietMissing = true
+	}
+}
+
+// NewOptions creates Options from the defaults and the given options.
+// The active profiles default to the GONFIG_PROFILES environment variable.
+// Parameters:
+//   - opts: Options to apply
+//
+// Returns:
+//   - *Options: Resulting options
+func NewOptions(opts ...Option) *Options {
+	o := &Options{
+		Profiles: splitProfiles(os.Getenv(ProfilesEnv)),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Formatter returns the formatter for the data of a source.
+// It is the formatter of the format set by WithFormat, otherwise the formatter registered in the registry for the
+// extension of the name, otherwise a format.Sniffer detecting the format from the data.
+// The options set by WithFormatOptions are applied to it, and gzip or zstd data is decompressed before it is parsed:
+// the compression extension of names such as "config.yaml.gz" is ignored when the format is resolved,
+// and compressed data is also detected by its magic bytes.
+// Parameters:
+//   - name: Name of the source (file name, Consul key or Nacos dataId)
+//
+// Returns:
+//   - format.Formatter: Formatter for the data
+//   - error: Error if no formatter is registered for the format or extension, or if a format option does not apply
+func (o *Options) Formatter(name string) (format.Formatter, error) {
+	ext := o.Format
+	if ext == "" {
+		ext = o.Ext(name)
+	}
+	if ext == "" {
+		return o.WrapFormatter(format.Sniffer{Registry: o.FormatRegistry()}, name)
+	}
+	formatter, ok := o.FormatRegistry().Get(ext)
+	if !ok {
+		return nil, fmt.Errorf("config: not found formatter for %s", ext)
+	}
+	return o.WrapFormatter(formatter, name)
+}
+
+// Ext returns the extension selecting the format of a source, ignoring a compression extension
+// Parameters:
+//   - name: Name of the source, e.g. "config.yaml.gz"
+//
+// Returns:
+//   - string: Extension without the dot, e.g. "yaml", empty if the name has none
+func (o *Options) Ext(name string) string {
+	name, _ = format.SplitCompression(name)
+	return strings.TrimPrefix(path.Ext(name), ".")
+}
+
+// WrapFormatter applies the options set by WithFormatOptions to the formatter of a source,
+// and decompresses data before the other options see it, see Formatter.
+// Resources finding formatters by other means, e.g. from metadata stored with the data, use it directly.
+// Parameters:
+//   - formatter: Formatter of the format of the source
+//   - name: Name of the source, a compression extension requires compressed data
+//
+// Returns:
+//   - format.Formatter: Wrapped formatter
+//   - error: Error if a format option does not apply to the formatter, e.g. format.WithStrict
+func (o *Options) WrapFormatter(formatter format.Formatter, name string) (format.Formatter, error) {
+	for _, opt := range o.FormatOptions {
+		var err error
+		if formatter, err = opt(formatter); err != nil {
+			return nil, err
+		}
+	}
+	_, compression := format.SplitCompression(name)
+	return format.WithDecompression(compression)(formatter)
+}
+
+// FormatRegistry returns the registry providing the formatters
+// Returns:
+//   - *format.Registry: Registry set by WithRegistry, or format.DefaultRegistry
+func (o *Options) FormatRegistry() *format.Registry {
+	if o.Registry == nil {
+		return format.DefaultRegistry
+	}
+	return o.Registry
+}
+
+// ProfileName returns the name of the profile-specific variant of a source,
+// using the separator set by WithProfileSeparator, see the ProfileName function.
+// Parameters:
+//   - name: Name of the base source (file name, Consul key or Nacos dataId)
+//   - profile: Profile name
+//
+// Returns:
+//   - string: Name of the profile-specific source, e.g. "config-prod.yaml" with the separator "-"
+func (o *Options) ProfileName(name string, profile string) string {
+	separator := o.ProfileSeparator
+	if separator == "" {
+		separator = "."
+	}
+	return profileName(name, separator, profile)
+}
+
+// ProfileName returns the name of the profile-specific variant of a source with the default "." separator.
+// The profile is inserted before the extension, e.g. "conf/config.yaml" becomes "conf/config.prod.yaml".
+// Names without an extension get the profile appended, e.g. "api-config" becomes "api-config.prod".
+// A compression extension is kept last, e.g. "config.yaml.gz" becomes "config.prod.yaml.gz".
+// Parameters:
+//   - name: Name of the base source (file name, Consul key or Nacos dataId)
+//   - profile: Profile name
+//
+// Returns:
+//   - string: Name of the profile-specific source
+func ProfileName(name string, profile string) string {
+	return profileName(name, ".", profile)
+}
+
+// profileName inserts the separator and the profile before the extension of a name, keeping a compression extension last
+func profileName(name string, separator string, profile string) string {
+	name, compression := format.SplitCompression(name)
+	if compression != "" {
+		compression = "." + compression
+	}
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + separator + profile + ext + compression
+}
+
+// splitProfiles splits a comma-separated list of profiles, dropping empty entries
+func splitProfiles(value string) []string {
+	var profiles []string
+	for _, profile := range strings.Split(value, ",") {
+		profile = strings.TrimSpace(profile)
+		if profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,94 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyacen/gonfig/format"
+)
+
+func TestProfileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		expected string
+	}{
+		{"config.yaml", "prod", "config.prod.yaml"},
+		{"conf/config.yaml", "eu", "conf/config.eu.yaml"},
+		{"service/api/config", "prod", "service/api/config.prod"},
+		{"api-config", "prod", "api-config.prod"},
+		{"config.yaml.gz", "prod", "config.prod.yaml.gz"},
+		{"routes.zst", "prod", "routes.prod.zst"},
+	}
+	for _, tt := range tests {
+		if got := ProfileName(tt.name, tt.profile); got != tt.expected {
+			t.Errorf("ProfileName(%q, %q) = %q; want %q", tt.name, tt.profile, got, tt.expected)
+		}
+	}
+}
+
+func TestOptions_ProfileName(t *testing.T) {
+	tests := []struct {
+		opts     []Option
+		name     string
+		expected string
+	}{
+		{nil, "app.yaml", "app.prod.yaml"},
+		{[]Option{WithProfileSeparator("-")}, "app.yaml", "app-prod.yaml"},
+		{[]Option{WithProfileSeparator("-")}, "conf/application.properties", "conf/application-prod.properties"},
+		{[]Option{WithProfileSeparator("-")}, "api-config", "api-config-prod"},
+		{[]Option{WithProfileSeparator("-")}, "app.yaml.gz", "app-prod.yaml.gz"},
+	}
+	for _, tt := range tests {
+		if got := NewOptions(tt.opts...).ProfileName(tt.name, "prod"); got != tt.expected {
+			t.Errorf("ProfileName(%q, %q) = %q; want %q", tt.name, "prod", got, tt.expected)
+		}
+	}
+}
+
+func TestNewOptions_ProfilesEnv(t *testing.T) {
+	t.Setenv(ProfilesEnv, " prod, ,eu ")
+	if got := NewOptions().Profiles; !reflect.DeepEqual(got, []string{"prod", "eu"}) {
+		t.Errorf("expected [prod eu]; got %v", got)
+	}
+	if got := NewOptions(WithProfiles("dev")).Profiles; !reflect.DeepEqual(got, []string{"dev"}) {
+		t.Errorf("expected [dev]; got %v", got)
+	}
+}
+
+func TestOptions_Formatter(t *testing.T) {
+	if _, err := NewOptions().Formatter("config.unknown"); err == nil {
+		t.Error("expected error for unknown extension")
+	}
+	formatter, err := NewOptions().Formatter("service/api/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := formatter.Parse([]byte(`{"key": "value"}`)); err != nil || value.GetFields()["key"].GetStringValue() != "value" {
+		t.Errorf("expected the format to be sniffed; got %v, %v", value, err)
+	}
+	if _, err := NewOptions(WithFormat("unknown")).Formatter("config.yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if _, err := NewOptions(WithFormatOptions(format.WithStrict())).Formatter("config.json"); err == nil {
+		t.Error("expected error for format option not applying to the formatter")
+	}
+}
+
+func TestOptions_Registry(t *testing.T) {
+	registry := format.NewRegistry()
+	registry.Register("conf", format.Sniffer{})
+	if _, err := NewOptions(WithRegistry(registry)).Formatter("app.conf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewOptions().Formatter("app.conf"); err == nil {
+		t.Error("expected error for extension only registered in another registry")
+	}
+	formatter, err := NewOptions(WithRegistry(registry)).Formatter("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := formatter.Parse([]byte(`{"key": "value"}`)); err == nil {
+		t.Error("expected sniffing to only use the formats of the registry")
+	}
+}
@@ -4,6 +4,7 @@ package resource
 import (
 	"context"
 
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -42,3 +43,60 @@ type Resource interface {
 	//   - error: Immediate error if watch setup fails
 	Watch(ctx context.Context, notifyFunc NotifyFunc, errFunc ErrFunc) (StopFunc, error)
 }
+
+// Transformer transforms the parsed configuration data of a resource, e.g. by decrypting encrypted values.
+// Resources built on Core apply transformers set with WithTransformers, any other resource can be
+// decorated with the Transform middleware. Transformers producing secrets mark them with format.MarkSensitive
+// in the context they are called with, so that conversion errors do not contain them.
+type Transformer interface {
+	// Transform returns the transformed configuration data.
+	// Args:
+	//   - ctx: Context for cancellation and timeouts
+	//   - value: Parsed configuration data, it must not be modified
+	// Returns:
+	//   - *structpb.Struct: Transformed configuration data
+	//   - error: Transformation error if any
+	Transform(ctx context.Context, value *structpb.Struct) (*structpb.Struct, error)
+}
+
+// MessageResource is implemented by resources able to decode their data directly into typed messages.
+// With a format.MessageFormatter, such as the protobuf text and wire formats, this bypasses the
+// Struct representation and keeps full type fidelity. gonfig.Load and gonfig.Watch prefer it when available.
+type MessageResource interface {
+	Resource
+
+	// LoadMessage retrieves the current configuration state into a message.
+	// The message type is remembered and used to parse data pushed while watching.
+	// Args:
+	//   - ctx: Context for cancellation and timeouts
+	//   - message: Message the configuration data is decoded into
+	// Returns:
+	//   - error: Loading error if any
+	LoadMessage(ctx context.Context, message proto.Message) error
+
+	// DecodeMessage decodes the configuration state of the latest load or notification into a message.
+	// Args:
+	//   - message: Message the configuration data is decoded into
+	// Returns:
+	//   - error: Decoding error if any
+	DecodeMessage(message proto.Message) error
+
+	// RegisterMessage registers the type of the message the configuration data is decoded into,
+	// so that data can be parsed by Load and Watch without a previous LoadMessage.
+	// Args:
+	//   - message: Message of the type the configuration data is decoded into
+	RegisterMessage(message proto.Message)
+}
+
+// ValueDecoder is implemented by MessageResources able to decode a configuration they loaded or notified as
+// DecodeMessage does, even if the configuration has changed since. gonfig.Watch uses it to decode exactly the
+// notified configuration when notifications race with reloads.
+type ValueDecoder interface {
+	// DecodeValue decodes a configuration returned by Load or passed to the NotifyFunc of Watch into a message.
+	// Args:
+	//   - value: Configuration data returned by Load or notified by Watch
+	//   - message: Message the configuration data is decoded into
+	// Returns:
+	//   - error: Decoding error if any
+	DecodeValue(value *structpb.Struct, message proto.Message) error
+}
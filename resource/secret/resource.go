@@ -0,0 +1,260 @@
+// Package secret provides a configuration resource mapping the files of a key-per-file directory to fields,
+// such as Kubernetes Secret volumes, Docker secrets in /run/secrets or systemd credentials in $CREDENTIALS_DIRECTORY
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/soyacen/gonfig/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ resource.Resource = (*Resource)(nil)
+
+// Resource represents a configuration whose fields are the files of a directory
+type Resource struct {
+	// dir is the path of the directory
+	dir string
+	// separator splits file names into nested fields, empty to keep file names as keys
+	separator string
+	// base64 decodes the contents of the files
+	base64 bool
+	// tracker serializes loads and remembers the latest configuration data, used to detect changes
+	tracker resource.Tracker
+}
+
+// Load reads the files of the directory, mapping every file name to a field whose value is the file content
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//
+// Returns:
+//   - *structpb.Struct: Configuration data, empty if the directory has no files
+//   - error: Any error that occurred while reading the directory or decoding a file
+func (r *Resource) Load(ctx context.Context) (*structpb.Struct, error) {
+	return r.tracker.Load(ctx, r.load)
+}
+
+// load reads the files of the directory in lexical order.
+// Hidden files and directories are skipped, such as the "..data" symbolic link and the timestamped
+// directories of Kubernetes volumes, whose files are reached through the symbolic links of the keys.
+func (r *Resource) load(context.Context) (*structpb.Struct, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	value := &structpb.Struct{Fields: make(map[string]*structpb.Value)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		filename := filepath.Join(r.dir, name)
+		// Follow symbolic links to the files of the current version
+		info, err := os.Stat(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed since the directory was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := r.decode(filename, data)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.set(value, name, content); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// decode trims the trailing newlines of a file content, added by editors and `echo`, and decodes it from base64 if enabled
+func (r *Resource) decode(filename string, data []byte) (string, error) {
+	content := strings.TrimRight(string(data), "\r\n")
+	if !r.base64 {
+		return content, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", fmt.Errorf("gonfig: %s: invalid base64 value: %w", filename, err)
+	}
+	return string(decoded), nil
+}
+
+// set sets the field named by a file name, split into nested fields at the separator
+func (r *Resource) set(value *structpb.Struct, name string, content string) error {
+	keys := []string{name}
+	if r.separator != "" {
+		keys = strings.Split(name, r.separator)
+	}
+	if slices.Contains(keys, "") {
+		return fmt.Errorf("gonfig: %s: empty field name", filepath.Join(r.dir, name))
+	}
+	fields := value.GetFields()
+	for _, key := range keys[:len(keys)-1] {
+		field, ok := fields[key]
+		if !ok {
+			field = structpb.NewStructValue(&structpb.Struct{Fields: make(map[string]*structpb.Value)})
+			fields[key] = field
+		}
+		nested := field.GetStructValue()
+		if nested == nil {
+			return fmt.Errorf("gonfig: %s: field %s is both a value and an object", filepath.Join(r.dir, name), key)
+		}
+		fields = nested.GetFields()
+	}
+	key := keys[len(keys)-1]
+	if _, ok := fields[key]; ok {
+		return fmt.Errorf("gonfig: %s: field %s is both a value and an object", filepath.Join(r.dir, name), key)
+	}
+	fields[key] = structpb.NewStringValue(content)
+	return nil
+}
+
+// Watch monitors the directory and reloads the configuration when any of its entries changes,
+// including the swap of the "..data" symbolic link by which Kubernetes updates mounted Secrets
+// Parameters:
+//   - ctx: Context for cancellation
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+//
+// Returns:
+//   - resource.StopFunc: Function to stop watching
+//   - error: Any immediate error during setup
+func (r *Resource) Watch(ctx context.Context, notifyFunc resource.NotifyFunc, errFunc resource.ErrFunc) (resource.StopFunc, error) {
+	// Validate arguments and set default error handler
+	errFunc, err := resource.PrepareWatch(ctx, "secret", notifyFunc, errFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize filesystem watcher
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// Watch the directory, events of files reached through symbolic links are reported for the links
+	// or, on Kubernetes, for the "..data" link swapped to a new version
+	if err := fsWatcher.Add(r.dir); err != nil {
+		_ = fsWatcher.Close()
+		return nil, err
+	}
+
+	// Create stop function
+	stop, stopC := resource.NewStopFunc()
+
+	// Start watching in a separate goroutine
+	go func() {
+		// Ensure watcher is closed when goroutine exits
+		defer func() {
+			if err := fsWatcher.Close(); err != nil {
+				errFunc(err)
+			}
+		}()
+
+		// Event loop
+		for {
+			select {
+			case <-ctx.Done():
+				// Context cancelled, exit goroutine
+				errFunc(ctx.Err())
+				return
+
+			case <-stopC:
+				// Stop signal received, exit goroutine
+				return
+
+			case err, ok := <-fsWatcher.Errors:
+				// Error from filesystem watcher
+				if !ok {
+					return
+				}
+				errFunc(err)
+
+			case _, ok := <-fsWatcher.Events:
+				// Any entry was added, changed, removed or renamed, unchanged values are not notified
+				if !ok {
+					return
+				}
+				r.tracker.Reload(ctx, r.load, notifyFunc, errFunc)
+			}
+		}
+	}()
+
+	return stop, nil
+}
+
+// options holds the key mapping settings of the resource
+type options struct {
+	separator string
+	base64    bool
+}
+
+// Option defines the function type for configuring the resource.
+type Option func(*options)
+
+// WithSeparator splits file names into nested fields at the separator,
+// e.g. the file "redis.password" populates redis.password for the separator ".".
+// Parameters:
+//   - separator: Separator of nested fields, e.g. "." or "__"
+func WithSeparator(separator string) Option {
+	return func(o *options) {
+		o.separator = separator
+	}
+}
+
+// WithBase64 decodes the contents of the files from standard base64, e.g. for secrets stored encoded.
+// Binary values can be kept encoded instead, protojson decodes base64 strings into bytes fields.
+func WithBase64() Option {
+	return func(o *options) {
+		o.base64 = true
+	}
+}
+
+// New creates a new key-per-file configuration resource
+// Every regular file of the directory is a field named after the file, whose value is the file content as a string
+// without trailing newlines. Hidden files are skipped.
+// Parameters:
+//   - dir: Path of the directory, e.g. "/run/secrets" or os.Getenv("CREDENTIALS_DIRECTORY")
+//   - opts: Options such as WithSeparator and WithBase64
+//
+// Returns:
+//   - *Resource: New key-per-file resource instance
+//   - error: Error if the directory is empty
+func New(dir string, opts ...Option) (*Resource, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("config: secret directory is empty")
+	}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Return new resource instance
+	return &Resource{
+		dir:       filepath.Clean(dir),
+		separator: o.separator,
+		base64:    o.base64,
+	}, nil
+}
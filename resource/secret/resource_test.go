@@ -0,0 +1,194 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// writeFiles writes files into a directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeVolume writes files the way the kubelet updates a Secret volume: into a new timestamped
+// directory, then swapping the "..data" symbolic link to it and linking every key to "..data"
+func writeVolume(t *testing.T, dir string, version string, files map[string]string) {
+	t.Helper()
+	versionDir := filepath.Join(dir, "..2026_10_18_"+version)
+	if err := os.Mkdir(versionDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, versionDir, files)
+	if err := os.Symlink(filepath.Base(versionDir), filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	oldVersionDir, _ := os.Readlink(filepath.Join(dir, "..data"))
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join("..data", name), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if oldVersionDir != "" {
+		if err := os.RemoveAll(filepath.Join(dir, oldVersionDir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		opts      []Option
+		expected  map[string]any
+		expectErr string
+	}{
+		{
+			name:     "Keys",
+			files:    map[string]string{"redis.password": "s3cret\n", "token": "abc\r\n", ".hidden": "x"},
+			expected: map[string]any{"redis.password": "s3cret", "token": "abc"},
+		},
+		{
+			name:     "Separator",
+			files:    map[string]string{"redis.password": "s3cret\n", "redis.user": "admin", "token": "abc"},
+			opts:     []Option{WithSeparator(".")},
+			expected: map[string]any{"redis": map[string]any{"password": "s3cret", "user": "admin"}, "token": "abc"},
+		},
+		{
+			name:     "Base64",
+			files:    map[string]string{"password": "czNjcmV0\n"},
+			opts:     []Option{WithBase64()},
+			expected: map[string]any{"password": "s3cret"},
+		},
+		{
+			name:      "Invalid Base64",
+			files:     map[string]string{"password": "s3cret!"},
+			opts:      []Option{WithBase64()},
+			expectErr: "password: invalid base64 value",
+		},
+		{
+			name:      "Conflict",
+			files:     map[string]string{"redis": "localhost", "redis.password": "s3cret"},
+			opts:      []Option{WithSeparator(".")},
+			expectErr: "field redis is both a value and an object",
+		},
+		{
+			name:      "Empty Field Name",
+			files:     map[string]string{"redis..password": "s3cret"},
+			opts:      []Option{WithSeparator(".")},
+			expectErr: "empty field name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeFiles(t, tempDir, tt.files)
+			resource, err := New(tempDir, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := resource.Load(context.Background())
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Errorf("expected error %q; got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.expected, value.AsMap()) {
+				t.Errorf("expected %v; got %v", tt.expected, value.AsMap())
+			}
+		})
+	}
+}
+
+func TestLoad_Volume(t *testing.T) {
+	tempDir := t.TempDir()
+	writeVolume(t, tempDir, "1", map[string]string{"username": "admin", "password": "s3cret"})
+
+	resource, err := New(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := resource.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"username": "admin", "password": "s3cret"}
+	if !reflect.DeepEqual(expected, value.AsMap()) {
+		t.Errorf("expected %v; got %v", expected, value.AsMap())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tempDir := t.TempDir()
+	writeVolume(t, tempDir, "1", map[string]string{"password": "s3cret"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resource, err := New(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *structpb.Struct, 16)
+	notifyFunc := func(newValue *structpb.Struct) {
+		c <- newValue
+	}
+	errFunc := func(err error) {
+		t.Errorf("Error: %v", err)
+	}
+	stop, err := resource.Watch(ctx, notifyFunc, errFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(ctx)
+
+	// wait waits for the configuration to become expected
+	wait := func(expected map[string]any) {
+		t.Helper()
+		for {
+			select {
+			case value := <-c:
+				if reflect.DeepEqual(expected, value.AsMap()) {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("expected %v; got timeout", expected)
+			}
+		}
+	}
+
+	// Rotate the secret by swapping the "..data" symbolic link
+	writeVolume(t, tempDir, "2", map[string]string{"password": "rotated"})
+	wait(map[string]any{"password": "rotated"})
+
+	// Add a key
+	writeVolume(t, tempDir, "3", map[string]string{"password": "rotated", "token": "abc"})
+	wait(map[string]any{"password": "rotated", "token": "abc"})
+}
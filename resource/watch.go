@@ -0,0 +1,95 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PrepareWatch validates the arguments of Watch, for resources that do not embed a Core
+// Parameters:
+//   - ctx: Context for cancellation
+//   - name: Kind of source in the log messages of the default error handler (e.g., "dir", "secret")
+//   - notifyFunc: Callback function for configuration updates, must not be nil
+//   - errFunc: Callback function for error reporting, a logging default is used if nil
+//
+// Returns:
+//   - ErrFunc: Error handler to use while watching
+//   - error: Error if notifyFunc is nil or the context is already cancelled
+func PrepareWatch(ctx context.Context, name string, notifyFunc NotifyFunc, errFunc ErrFunc) (ErrFunc, error) {
+	// Validate notify function
+	if notifyFunc == nil {
+		return nil, fmt.Errorf("gonfig: notifyFunc is nil")
+	}
+
+	// Set default error handler if none provided
+	if errFunc == nil {
+		errFunc = func(err error) {
+			slog.Error("gonfig: failed to watch "+name, slog.String("error", err.Error()))
+		}
+	}
+
+	// Check if context is already cancelled
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return errFunc, nil
+}
+
+// Tracker remembers the latest configuration data of a resource, so that reloads only notify changes.
+// It suits resources assembling their data from several sources, e.g. the files of a directory,
+// resources fetching raw bytes embed a Core instead. The zero value is ready to use.
+type Tracker struct {
+	// mutex serializes loads and protects value
+	mutex sync.Mutex
+	// value is the latest configuration data, used to detect changes
+	value *structpb.Struct
+}
+
+// Load loads the configuration data and remembers it.
+// Loads are serialized, so load may use state of the resource without further locking.
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - load: Function loading the configuration data
+//
+// Returns:
+//   - *structpb.Struct: Configuration data
+//   - error: Error of load
+func (t *Tracker) Load(ctx context.Context, load LoadFunc) (*structpb.Struct, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.value = value
+	return value, nil
+}
+
+// Reload loads the configuration data again and notifies subscribers if it has changed.
+// Errors are reported to errFunc and the last configuration data is kept.
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - load: Function loading the configuration data
+//   - notifyFunc: Callback function for configuration updates
+//   - errFunc: Callback function for error reporting
+func (t *Tracker) Reload(ctx context.Context, load LoadFunc, notifyFunc NotifyFunc, errFunc ErrFunc) {
+	t.mutex.Lock()
+	value, err := load(ctx)
+	if err != nil {
+		t.mutex.Unlock()
+		errFunc(err)
+		return
+	}
+	if t.value != nil && proto.Equal(t.value, value) {
+		t.mutex.Unlock()
+		return
+	}
+	t.value = value
+	t.mutex.Unlock()
+	notifyFunc(value)
+}
@@ -0,0 +1,66 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestTracker(t *testing.T) {
+	var tracker Tracker
+	name, loadErr := "a", error(nil)
+	load := func(context.Context) (*structpb.Struct, error) {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return structpb.NewStruct(map[string]any{"name": name})
+	}
+	var notified []string
+	notifyFunc := func(value *structpb.Struct) {
+		notified = append(notified, value.GetFields()["name"].GetStringValue())
+	}
+	var errs []error
+	errFunc := func(err error) {
+		errs = append(errs, err)
+	}
+
+	if _, err := tracker.Load(context.Background(), load); err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged data does not notify
+	tracker.Reload(context.Background(), load, notifyFunc, errFunc)
+	name = "b"
+	tracker.Reload(context.Background(), load, notifyFunc, errFunc)
+	// Errors are reported and the last data is kept
+	loadErr = errors.New("unavailable")
+	tracker.Reload(context.Background(), load, notifyFunc, errFunc)
+	loadErr = nil
+	tracker.Reload(context.Background(), load, notifyFunc, errFunc)
+	if !reflect.DeepEqual([]string{"b"}, notified) {
+		t.Errorf("expected notifications [b]; got %v", notified)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error; got %v", errs)
+	}
+}
+
+func TestPrepareWatch(t *testing.T) {
+	if _, err := PrepareWatch(context.Background(), "test", nil, nil); err == nil {
+		t.Error("expected error for nil notifyFunc")
+	}
+	errFunc, err := PrepareWatch(context.Background(), "test", func(*structpb.Struct) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errFunc == nil {
+		t.Error("expected default errFunc")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := PrepareWatch(ctx, "test", func(*structpb.Struct) {}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+}